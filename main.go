@@ -1,12 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/CristianCurteanu/asana-extractor/pkg/asana"
@@ -18,6 +29,26 @@ var (
 	asanaAccessToken = flag.String("asana-access-token", "", "This is the Asana PAT (required)\nCheck this page how to set it up https://developers.asana.com/docs/personal-access-token")
 	asanaAPIHost     = flag.String("asana-host", "https://app.asana.com/api/1.0", "This parameter is used in case the Asana API URL will be different that the one provided from official docs")
 	extractionPeriod = flag.String("extraction-period", "30s", "Period of time between extraction jobs; it's either 30s or 5m")
+	retryAttempts    = flag.Int("retry-attempts", 5, "Maximum attempts of the Asana API requests throttled or failed on the Asana side, and of the reads failed with network errors")
+	retryMaxDelay    = flag.Duration("retry-max-delay", 30*time.Second, "Maximum delay between the attempts of a failed Asana API request, unless Asana asks for a longer one")
+	proxyURL         = flag.String("proxy-url", "", "URL of the proxy to send the Asana API requests through; the HTTPS_PROXY environment variable is used if empty")
+	caBundle         = flag.String("ca-bundle", "", "Path to a PEM file with the certificate authorities to verify the Asana API, or the proxy, against; the system ones are used if empty")
+	clientCert       = flag.String("client-cert", "", "Path to a PEM client certificate, for the proxies asking for mutual TLS, along with its private key")
+	clientKey        = flag.String("client-key", "", "Path to the PEM private key of the client certificate")
+	requestTimeout   = flag.Duration("request-timeout", 0, "Timeout of every attempt of an Asana API request; requests do not time out if zero")
+	userAgent        = flag.String("user-agent", "asana-extractor", "User-Agent of the Asana API requests")
+
+	archiveAttachments     = flag.Bool("archive-attachments", false, "Download the content of the task attachments into the output directory")
+	attachmentsMaxSize     = flag.Int64("attachments-max-size", 25<<20, "Maximum size, in bytes, of the archived attachments")
+	webhookListenAddr      = flag.String("webhook-listen-addr", "", "Address to serve the Asana webhooks on, like :8080; webhooks are not used if empty")
+	webhookBaseURL         = flag.String("webhook-base-url", "", "Public URL at which Asana reaches the webhooks server, like https://extractor.example.com")
+	webhookResources       = flag.String("webhook-resources", "", "Comma separated list of resource GIDs to register webhooks for; all the projects if empty")
+	fieldProfiles          = flag.String("field-profiles", "", "Comma separated list of resource types and the lean or rich profile of their extracted fields, like project=lean,task=rich; supported for the workspace, project and task types, which are rich if missing")
+	incrementalSync        = flag.Bool("incremental-sync", false, "Follow the project and task changes through the Asana Events API, and crawl them fully only when the changes are unknown; the other jobs crawling every project or task run every -crawl-period instead")
+	crawlPeriod            = flag.Duration("crawl-period", time.Hour, "Period of the users, sections, stories, tags, time tracking, status updates, custom field settings and attachments jobs with -incremental-sync, as they crawl every project or task")
+	auditLogWorkspaces     = flag.String("audit-log-workspaces", "", "Comma separated list of enterprise workspace GIDs, whose audit log events are appended to the output directory")
+	auditLogStartAt        = flag.String("audit-log-start-at", "", "RFC 3339 timestamp of the oldest audit log event to export; all of them are exported if empty")
+	attachmentsContentType = flag.String("attachments-content-types", "", "Comma separated list of archived attachment content types, like application/pdf,image/*; all of them are archived if empty")
 )
 
 func main() {
@@ -36,57 +67,309 @@ func main() {
 		log.Fatalf("unable to initialize without Asana API Token; check this page how to set it up https://developers.asana.com/docs/personal-access-token")
 	}
 
-	apiClient := asana.NewAPIClient(*asanaAPIHost, *asanaAccessToken)
+	clientOptions, err := apiClientOptions()
+	if err != nil {
+		log.Fatalf("unable to configure the Asana API client, err=%q", err)
+	}
+	apiClient, err := asana.NewAPIClient(*asanaAPIHost, *asanaAccessToken, clientOptions...)
+	if err != nil {
+		log.Fatalf("unable to configure the Asana API client, err=%q", err)
+	}
 
 	log.Printf("Asana API Extractor running (pid: %d)", os.Getpid())
 
 	// Step 3: Setup the Asana Extractor, and inject it to Periodic Extractor
-	asanaExtractor := asana.NewExtractor(apiClient)
+	profiles, err := asana.ParseFieldProfiles(*fieldProfiles)
+	if err != nil {
+		log.Fatalf("please specify proper field profiles, err=%q", err)
+	}
+	asanaExtractor := asana.NewExtractor(apiClient, profiles)
 
 	// Step 4: Run the Periodic Extractor
 	period, found := ticker.GetExtractionPeriod(*extractionPeriod)
 	if !found {
 		log.Fatal("please specify proper period config value, either `30s` or `5m`")
 	}
-	scheduler := ticker.NewScheduler()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scheduler := ticker.NewScheduler(ctx)
 	defer scheduler.Stop()
 
+	// a revoked access token fails every job the same way, so there is no
+	// point in running them again
+	runJob := func(name string, period time.Duration, handler ticker.Handler) {
+		scheduler.Run(name, period, func(ctx context.Context) error {
+			err := handler(ctx)
+			if asana.IsUnauthorized(err) {
+				log.Printf("the Asana access token is not authorized anymore, stopping")
+				stop()
+			}
+
+			return err
+		})
+	}
+
+	// the events only tell the project and task changes, so the other jobs
+	// crawling every project or task are slowed down along with them
+	fullCrawlPeriod := period
+	if *incrementalSync {
+		fullCrawlPeriod = *crawlPeriod
+	}
+
 	fileStorage := storage.NewFile(*outputDir)
-	scheduler.Run("get all users", period, func() error {
-		users, err := asanaExtractor.GetAllUsers()
+	runJob("get all workspaces", period, snapshotJob(fileStorage, "workspaces", asanaExtractor.GetAllWorkspaces))
+	runJob("get all users", fullCrawlPeriod, snapshotJob(fileStorage, "users", asanaExtractor.GetAllUsers))
+	projectsJob := snapshotJob(fileStorage, "projects", asanaExtractor.GetAllProjects)
+	tasksJob := snapshotJob(fileStorage, "tasks", asanaExtractor.GetAllTasks)
+	if *incrementalSync {
+		// projects and tasks are crawled only when the events can not tell
+		// what changed since the last crawl
+		events := asana.NewEventsConsumer(apiClient, asanaExtractor, fileStorage, func(ctx context.Context) error {
+			err := projectsJob(ctx)
+			if err != nil {
+				return err
+			}
+
+			return tasksJob(ctx)
+		})
+		runJob("sync events", period, events.Sync)
+	} else {
+		runJob("get all projects", period, projectsJob)
+		runJob("get all tasks", period, tasksJob)
+	}
+	runJob("get all sections", fullCrawlPeriod, snapshotJob(fileStorage, "sections", asanaExtractor.GetAllSections))
+	runJob("get all teams", period, snapshotJob(fileStorage, "teams", asanaExtractor.GetAllTeams))
+	runJob("get all portfolios", period, snapshotJob(fileStorage, "portfolios", asanaExtractor.GetAllPortfolios))
+	runJob("get all goals", period, snapshotJob(fileStorage, "goals", asanaExtractor.GetAllGoals))
+	runJob("get all stories", fullCrawlPeriod, snapshotJob(fileStorage, "stories", asanaExtractor.GetAllStories))
+	runJob("get all tags", fullCrawlPeriod, snapshotJob(fileStorage, "tags", asanaExtractor.GetAllTags))
+	runJob("get time tracking report", fullCrawlPeriod, snapshotJob(fileStorage, "time_tracking", asanaExtractor.GetTimeTrackingReport))
+	runJob("get all status updates", fullCrawlPeriod, historyJob(fileStorage, "status_updates", asanaExtractor.GetAllStatusUpdates, func(s asana.StatusUpdate) string { return s.GID }))
+	runJob("get all custom fields", period, snapshotJob(fileStorage, "custom_fields", asanaExtractor.GetAllCustomFields))
+	runJob("get all custom field settings", fullCrawlPeriod, snapshotJob(fileStorage, "custom_field_settings", asanaExtractor.GetAllCustomFieldSettings))
+
+	getAllAttachments := asanaExtractor.GetAllAttachments
+	if *archiveAttachments {
+		var contentTypes []string
+		if *attachmentsContentType != "" {
+			contentTypes = strings.Split(*attachmentsContentType, ",")
+		}
+		archiver := asana.NewArchiver(apiClient, fileStorage, *attachmentsMaxSize, contentTypes)
+
+		getAllAttachments = func(ctx context.Context) ([]asana.Attachment, error) {
+			attachments, err := asanaExtractor.GetAllAttachments(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return archiver.ArchiveAttachments(ctx, attachments)
+		}
+	}
+	runJob("get all attachments", fullCrawlPeriod, snapshotJob(fileStorage, "attachments", getAllAttachments))
+
+	if *auditLogWorkspaces != "" {
+		var query asana.AuditLogQuery
+		if *auditLogStartAt != "" {
+			query.StartAt, err = time.Parse(time.RFC3339, *auditLogStartAt)
+			if err != nil {
+				log.Fatalf("please specify the audit log start in RFC 3339 format, err=%q", err)
+			}
+		}
+
+		for _, workspaceGID := range strings.Split(*auditLogWorkspaces, ",") {
+			auditLog := asana.NewAuditLogSyncer(apiClient, fileStorage, workspaceGID, query)
+			runJob(fmt.Sprintf("sync %s audit log", workspaceGID), period, auditLog.Sync)
+		}
+	}
+
+	if *webhookListenAddr != "" {
+		if *webhookBaseURL == "" {
+			log.Fatal("please specify the public webhooks URL, through the `-webhook-base-url` parameter")
+		}
+		go serveWebhooks(ctx, apiClient, asanaExtractor, fileStorage, profiles)
+	}
+
+	scheduler.Wait()
+}
+
+// apiClientOptions configures the Asana API client from the command line
+// parameters.
+func apiClientOptions() ([]asana.ClientOption, error) {
+	retryPolicy := asana.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = *retryAttempts
+	retryPolicy.MaxDelay = *retryMaxDelay
+
+	options := []asana.ClientOption{
+		asana.WithRetryPolicy(retryPolicy),
+		asana.WithUserAgent(*userAgent),
+		asana.WithTimeout(*requestTimeout),
+	}
+
+	if *proxyURL != "" {
+		u, err := url.Parse(*proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("malformed proxy URL: %w", err)
+		}
+		options = append(options, asana.WithProxy(u))
+	}
+
+	if *caBundle != "" {
+		data, err := os.ReadFile(*caBundle)
+		if err != nil {
+			return nil, err
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %q", *caBundle)
+		}
+		options = append(options, asana.WithRootCAs(rootCAs))
+	}
+
+	if *clientCert != "" {
+		certificate, err := tls.LoadX509KeyPair(*clientCert, *clientKey)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, asana.WithClientCertificates(certificate))
+	}
+
+	return options, nil
+}
+
+// serveWebhooks serves the Asana webhooks, and registers them once the server
+// is listening, as Asana sends the handshake while registering.
+func serveWebhooks(ctx context.Context, apiClient asana.APIClient, asanaExtractor asana.Extractor, fileStorage storage.File, profiles asana.FieldProfiles) {
+	receiver := asana.NewWebhookReceiver(apiClient, fileStorage, profiles)
+	server := &http.Server{
+		Handler:           receiver,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", *webhookListenAddr)
+	if err != nil {
+		log.Printf("failed to listen for webhooks, err=%q", err)
+		return
+	}
+	go server.Serve(listener)
+	go receiver.Run(ctx)
+	log.Printf("serving webhooks on %q", listener.Addr())
+
+	var resources []string
+	if *webhookResources != "" {
+		resources = strings.Split(*webhookResources, ",")
+	} else {
+		projects, err := asanaExtractor.GetAllProjects(ctx)
+		if err != nil {
+			log.Printf("failed to list the projects to register webhooks for, err=%q", err)
+		}
+		for _, project := range projects {
+			resources = append(resources, project.GID)
+		}
+	}
+
+	err = receiver.Register(ctx, *webhookBaseURL, resources)
+	if err != nil {
+		log.Printf("failed to register webhooks, err=%q", err)
+	}
+
+	<-ctx.Done()
+	server.Shutdown(context.Background())
+}
+
+// snapshotJob builds a scheduled job, which runs the extraction and stores its
+// result as JSON into a `<unix timestamp>_<name>.json` file.
+func snapshotJob[T any](fileStorage storage.File, name string, extract func(ctx context.Context) (T, error)) ticker.Handler {
+	return func(ctx context.Context) error {
+		res, err := extract(ctx)
 		if err != nil {
 			return err
 		}
 
-		usersData, err := json.MarshalIndent(users, "", "  ")
+		data, err := json.MarshalIndent(res, "", "  ")
 		if err != nil {
-			log.Printf("failed to marshal users to JSON, err=%q", err)
+			log.Printf("failed to marshal %s to JSON, err=%q", name, err)
 
 			return err
 		}
 
 		tn := time.Now().UTC()
 
-		return fileStorage.Store(fmt.Sprintf("%d_users.json", tn.Unix()), usersData)
-	})
+		return fileStorage.Store(fmt.Sprintf("%d_%s.json", tn.Unix(), name), data)
+	}
+}
+
+// historyJob builds a scheduled job, which runs the extraction and appends the
+// records that were not stored yet to the `<name>.jsonl` file, one JSON
+// record per line; the records are told apart by their key.
+func historyJob[T any](fileStorage storage.File, name string, extract func(ctx context.Context) ([]T, error), key func(T) string) ticker.Handler {
+	file := fmt.Sprintf("%s.jsonl", name)
+	var seen map[string]struct{}
+
+	return func(ctx context.Context) error {
+		if seen == nil {
+			stored, err := readHistoryKeys(fileStorage, file, key)
+			if err != nil {
+				return err
+			}
+			seen = stored
+		}
 
-	scheduler.Run("get all projects", period, func() error {
-		projects, err := asanaExtractor.GetAllProjects()
+		records, err := extract(ctx)
 		if err != nil {
 			return err
 		}
 
-		projectsData, err := json.MarshalIndent(projects, "", "  ")
+		var (
+			appended []T
+			keys     []string
+		)
+		for _, record := range records {
+			k := key(record)
+			if _, found := seen[k]; found || slices.Contains(keys, k) {
+				continue
+			}
+
+			appended = append(appended, record)
+			keys = append(keys, k)
+		}
+
+		err = storage.AppendJSONLines(fileStorage, file, appended)
 		if err != nil {
-			log.Printf("failed to marshal users to JSON, err=%q", err)
+			log.Printf("failed to append %s to the history, err=%q", name, err)
 
 			return err
 		}
+		for _, k := range keys {
+			seen[k] = struct{}{}
+		}
 
-		tn := time.Now().UTC()
+		return nil
+	}
+}
 
-		return fileStorage.Store(fmt.Sprintf("%d_projects.json", tn.Unix()), projectsData)
-	})
+// readHistoryKeys returns the keys of the records already stored into a
+// history file.
+func readHistoryKeys[T any](fileStorage storage.File, file string, key func(T) string) (map[string]struct{}, error) {
+	data, err := fileStorage.Read(file)
+	if err != nil {
+		return nil, err
+	}
 
-	scheduler.Wait()
+	keys := make(map[string]struct{})
+	for line := range bytes.Lines(data) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var record T
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %q history record: %w", file, err)
+		}
+		keys[key(record)] = struct{}{}
+	}
+
+	return keys, nil
 }
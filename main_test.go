@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/CristianCurteanu/asana-extractor/pkg/asana"
+	"github.com/CristianCurteanu/asana-extractor/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HistoryJob_AppendsOnlyNewRecords(t *testing.T) {
+	fs := storage.NewFile(t.TempDir())
+	key := func(s asana.StatusUpdate) string { return s.GID }
+	extracted := [][]asana.StatusUpdate{
+		{{GID: "1"}, {GID: "2"}},
+		{{GID: "2"}, {GID: "3"}, {GID: "3"}},
+		{{GID: "1"}, {GID: "3"}, {GID: "4"}},
+	}
+	extract := func(ctx context.Context) ([]asana.StatusUpdate, error) {
+		records := extracted[0]
+		extracted = extracted[1:]
+		return records, nil
+	}
+
+	job := historyJob(fs, "status_updates", extract, key)
+	require.NoError(t, job(context.Background()))
+	require.NoError(t, job(context.Background()))
+
+	// a restarted job picks up the records stored by the previous one
+	job = historyJob(fs, "status_updates", extract, key)
+	require.NoError(t, job(context.Background()))
+
+	data, err := fs.Read("status_updates.jsonl")
+	require.NoError(t, err)
+
+	var gids []string
+	for line := range strings.Lines(string(data)) {
+		var statusUpdate asana.StatusUpdate
+		require.NoError(t, json.Unmarshal([]byte(line), &statusUpdate))
+		gids = append(gids, statusUpdate.GID)
+	}
+	require.Equal(t, []string{"1", "2", "3", "4"}, gids)
+}
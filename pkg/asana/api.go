@@ -1,181 +1,438 @@
 package asana
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strconv"
+	"slices"
 	"time"
 
 	"github.com/CristianCurteanu/angler"
-	slumber "github.com/CristianCurteanu/slumber"
 )
 
-var (
-	errToManyRequests = errors.New("too many requests, retry")
-)
+var ErrAttachmentTooLarge = errors.New("attachment exceeds the maximum size")
 
 type APIClient interface {
-	ListProjects(query url.Values) ([]Project, *NextPage, error)
-	ListWorkspaces(query url.Values) ([]Workspace, *NextPage, error)
-	ListUsers(query url.Values) ([]User, *NextPage, error)
+	ListProjects(ctx context.Context, query url.Values) ([]Project, *NextPage, error)
+	ListWorkspaces(ctx context.Context, query url.Values) ([]Workspace, *NextPage, error)
+	ListUsers(ctx context.Context, query url.Values) ([]User, *NextPage, error)
+	ListTasks(ctx context.Context, query url.Values) ([]Task, *NextPage, error)
+	ListSections(ctx context.Context, projectGID string, query url.Values) ([]Section, *NextPage, error)
+	ListTeams(ctx context.Context, workspaceGID string, query url.Values) ([]Team, *NextPage, error)
+	ListTeamMemberships(ctx context.Context, teamGID string, query url.Values) ([]TeamMembership, *NextPage, error)
+	ListPortfolios(ctx context.Context, query url.Values) ([]Portfolio, *NextPage, error)
+	GetPortfolio(ctx context.Context, portfolioGID string, query url.Values) (Portfolio, error)
+	ListPortfolioItems(ctx context.Context, portfolioGID string, query url.Values) ([]Compact, *NextPage, error)
+	ListGoals(ctx context.Context, query url.Values) ([]Goal, *NextPage, error)
+	ListGoalRelationships(ctx context.Context, query url.Values) ([]GoalRelationship, *NextPage, error)
+	ListStories(ctx context.Context, taskGID string, query url.Values) ([]Story, *NextPage, error)
+	ListAttachments(ctx context.Context, query url.Values) ([]Attachment, *NextPage, error)
+	ListCustomFields(ctx context.Context, workspaceGID string, query url.Values) ([]CustomField, *NextPage, error)
+	ListCustomFieldSettings(ctx context.Context, projectGID string, query url.Values) ([]CustomFieldSetting, *NextPage, error)
+	ListTags(ctx context.Context, workspaceGID string, query url.Values) ([]Tag, *NextPage, error)
+	ListTagTasks(ctx context.Context, tagGID string, query url.Values) ([]Compact, *NextPage, error)
+	ListTimeTrackingEntries(ctx context.Context, taskGID string, query url.Values) ([]TimeTrackingEntry, *NextPage, error)
+	ListStatusUpdates(ctx context.Context, query url.Values) ([]StatusUpdate, *NextPage, error)
+	ListMemberships(ctx context.Context, query url.Values) ([]ProjectMembership, *NextPage, error)
+	ListWorkspaceMemberships(ctx context.Context, workspaceGID string, query url.Values) ([]WorkspaceMembership, *NextPage, error)
+	ListAuditLogEvents(ctx context.Context, workspaceGID string, query url.Values) ([]AuditLogEvent, *NextPage, error)
+	GetEvents(ctx context.Context, resourceGID, syncToken string) (EventsResponse, error)
+	GetTask(ctx context.Context, taskGID string, query url.Values) (Task, error)
+	GetProject(ctx context.Context, projectGID string, query url.Values) (Project, error)
+	CreateWebhook(ctx context.Context, webhook WebhookRequest) (Webhook, error)
+	SearchTasks(ctx context.Context, workspaceGID string, query url.Values) ([]Task, error)
+	Batch(ctx context.Context, reqs []BatchRequest) ([]BatchResult, error)
+	DownloadAttachment(ctx context.Context, downloadURL string, maxSize int64) (*Download, error)
+}
+
+// SyncTokenError is returned by GetEvents when the sync token is missing or
+// too old; Sync is the token to request the events from now on.
+type SyncTokenError struct {
+	Sync string
+}
+
+func (e *SyncTokenError) Error() string {
+	return "sync token missing or expired"
+}
+
+// Download is the content of a downloaded attachment.
+type Download struct {
+	ContentType string
+	Data        []byte
 }
 
 type apiClient struct {
 	host        string
 	accessToken string
+	httpClient  *http.Client
+	headers     map[string]string
+	retryPolicy RetryPolicy
+	limiter     *rateLimiter
 }
 
-func NewAPIClient(host, accessToken string) APIClient {
-	return &apiClient{host, accessToken}
-}
+// NewAPIClient builds an API client for the Asana API at host. By default,
+// it sends the requests through http.DefaultClient, retries them according
+// to DefaultRetryPolicy, and keeps them within the quotas of the Asana paid
+// plans. It fails when the options can not be applied together.
+func NewAPIClient(host, accessToken string, options ...ClientOption) (APIClient, error) {
+	config := clientConfig{
+		retryPolicy:         DefaultRetryPolicy(),
+		requestsPerMinute:   requestsPerMinute,
+		maxConcurrentReads:  maxConcurrentReads,
+		maxConcurrentWrites: maxConcurrentWrites,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+	if config.requestsPerMinute <= 0 || config.maxConcurrentReads <= 0 || config.maxConcurrentWrites <= 0 {
+		return nil, fmt.Errorf("invalid rate limit of %d requests per minute, %d concurrent reads and %d concurrent writes; all of them must be positive",
+			config.requestsPerMinute, config.maxConcurrentReads, config.maxConcurrentWrites)
+	}
 
-func (c *apiClient) ListUsers(query url.Values) ([]User, *NextPage, error) {
-	var errResp ErrorsResponse
-	handleStatusError := func(message string) angler.StatusHandlerFunc {
-		return handleErrorStatusWithResponse(&errResp, message)
+	httpClient, err := config.client()
+	if err != nil {
+		return nil, err
 	}
 
-	users, err := slumber.Retry(func() (MultipleResponse[User], error) {
+	return &apiClient{
+		host:        host,
+		accessToken: accessToken,
+		httpClient:  httpClient,
+		headers:     config.headers,
+		retryPolicy: config.retryPolicy,
+		limiter:     newRateLimiter(config.requestsPerMinute, config.maxConcurrentReads, config.maxConcurrentWrites),
+	}, nil
+}
 
-		url := fmt.Sprintf("%s/users?%s", c.host, query.Encode())
-		users, err := angler.Fetch[MultipleResponse[User]](
-			angler.WithURL(url),
-			angler.WithHeader("Authorization", fmt.Sprintf("Bearer %s", c.accessToken)),
-			angler.WithStatusHandler(http.StatusTooManyRequests, handleStatusTooManyRequests(50*time.Millisecond)),
-			angler.WithStatusHandler(http.StatusBadRequest, handleStatusError("missing of malformed parameter")),
-			angler.WithStatusHandler(http.StatusUnauthorized, handleStatusError("unauthorized")),
-			angler.WithStatusHandler(http.StatusNotFound, handleStatusError("not found")),
-			angler.WithStatusHandler(http.StatusInternalServerError, handleStatusError("internal error, try again later")),
-		)
-		if err != nil && errors.Is(err, errToManyRequests) {
-			return users, err
-		}
-		return users, nil
-	},
-		slumber.WithRetryPolicy(slumber.ExponentialBackoff),
-		slumber.WithDelay(50*time.Millisecond),
-		slumber.WithRetries(5),
-	)
+func (c *apiClient) ListUsers(ctx context.Context, query url.Values) ([]User, *NextPage, error) {
+	return fetchList[User](ctx, c, "/users", query)
+}
 
-	if err != nil {
-		return nil, nil, err
+func (c *apiClient) ListWorkspaceUsers(ctx context.Context, workspaceId string, query url.Values) ([]User, *NextPage, error) {
+	return fetchList[User](ctx, c, fmt.Sprintf("/workspaces/%s/users", workspaceId), query)
+}
+
+func (c *apiClient) ListWorkspaces(ctx context.Context, query url.Values) ([]Workspace, *NextPage, error) {
+	return fetchList[Workspace](ctx, c, "/workspaces", query)
+}
+
+func (c *apiClient) ListProjects(ctx context.Context, query url.Values) ([]Project, *NextPage, error) {
+	return fetchList[Project](ctx, c, "/projects", query)
+}
+
+func (c *apiClient) ListTasks(ctx context.Context, query url.Values) ([]Task, *NextPage, error) {
+	return fetchList[Task](ctx, c, "/tasks", query)
+}
+
+func (c *apiClient) ListSections(ctx context.Context, projectGID string, query url.Values) ([]Section, *NextPage, error) {
+	return fetchList[Section](ctx, c, fmt.Sprintf("/projects/%s/sections", projectGID), query)
+}
+
+func (c *apiClient) ListTeams(ctx context.Context, workspaceGID string, query url.Values) ([]Team, *NextPage, error) {
+	return fetchList[Team](ctx, c, fmt.Sprintf("/workspaces/%s/teams", workspaceGID), query)
+}
+
+func (c *apiClient) ListTeamMemberships(ctx context.Context, teamGID string, query url.Values) ([]TeamMembership, *NextPage, error) {
+	return fetchList[TeamMembership](ctx, c, fmt.Sprintf("/teams/%s/team_memberships", teamGID), query)
+}
+
+func (c *apiClient) ListPortfolios(ctx context.Context, query url.Values) ([]Portfolio, *NextPage, error) {
+	return fetchList[Portfolio](ctx, c, "/portfolios", query)
+}
+
+func (c *apiClient) GetPortfolio(ctx context.Context, portfolioGID string, query url.Values) (Portfolio, error) {
+	return fetchOne[Portfolio](ctx, c, fmt.Sprintf("/portfolios/%s", portfolioGID), query)
+}
+
+func (c *apiClient) ListPortfolioItems(ctx context.Context, portfolioGID string, query url.Values) ([]Compact, *NextPage, error) {
+	return fetchList[Compact](ctx, c, fmt.Sprintf("/portfolios/%s/items", portfolioGID), query)
+}
+
+func (c *apiClient) ListGoals(ctx context.Context, query url.Values) ([]Goal, *NextPage, error) {
+	return fetchList[Goal](ctx, c, "/goals", query)
+}
+
+func (c *apiClient) ListGoalRelationships(ctx context.Context, query url.Values) ([]GoalRelationship, *NextPage, error) {
+	return fetchList[GoalRelationship](ctx, c, "/goal_relationships", query)
+}
+
+func (c *apiClient) ListStories(ctx context.Context, taskGID string, query url.Values) ([]Story, *NextPage, error) {
+	return fetchList[Story](ctx, c, fmt.Sprintf("/tasks/%s/stories", taskGID), query)
+}
+
+func (c *apiClient) ListAttachments(ctx context.Context, query url.Values) ([]Attachment, *NextPage, error) {
+	return fetchList[Attachment](ctx, c, "/attachments", query)
+}
+
+func (c *apiClient) ListCustomFields(ctx context.Context, workspaceGID string, query url.Values) ([]CustomField, *NextPage, error) {
+	return fetchList[CustomField](ctx, c, fmt.Sprintf("/workspaces/%s/custom_fields", workspaceGID), query)
+}
+
+func (c *apiClient) ListCustomFieldSettings(ctx context.Context, projectGID string, query url.Values) ([]CustomFieldSetting, *NextPage, error) {
+	return fetchList[CustomFieldSetting](ctx, c, fmt.Sprintf("/projects/%s/custom_field_settings", projectGID), query)
+}
+
+func (c *apiClient) ListTags(ctx context.Context, workspaceGID string, query url.Values) ([]Tag, *NextPage, error) {
+	return fetchList[Tag](ctx, c, fmt.Sprintf("/workspaces/%s/tags", workspaceGID), query)
+}
+
+func (c *apiClient) ListTagTasks(ctx context.Context, tagGID string, query url.Values) ([]Compact, *NextPage, error) {
+	return fetchList[Compact](ctx, c, fmt.Sprintf("/tags/%s/tasks", tagGID), query)
+}
+
+func (c *apiClient) ListTimeTrackingEntries(ctx context.Context, taskGID string, query url.Values) ([]TimeTrackingEntry, *NextPage, error) {
+	return fetchList[TimeTrackingEntry](ctx, c, fmt.Sprintf("/tasks/%s/time_tracking_entries", taskGID), query)
+}
+
+func (c *apiClient) ListStatusUpdates(ctx context.Context, query url.Values) ([]StatusUpdate, *NextPage, error) {
+	return fetchList[StatusUpdate](ctx, c, "/status_updates", query)
+}
+
+func (c *apiClient) ListMemberships(ctx context.Context, query url.Values) ([]ProjectMembership, *NextPage, error) {
+	return fetchList[ProjectMembership](ctx, c, "/memberships", query)
+}
+
+func (c *apiClient) ListWorkspaceMemberships(ctx context.Context, workspaceGID string, query url.Values) ([]WorkspaceMembership, *NextPage, error) {
+	return fetchList[WorkspaceMembership](ctx, c, fmt.Sprintf("/workspaces/%s/workspace_memberships", workspaceGID), query)
+}
+
+func (c *apiClient) ListAuditLogEvents(ctx context.Context, workspaceGID string, query url.Values) ([]AuditLogEvent, *NextPage, error) {
+	return fetchList[AuditLogEvent](ctx, c, fmt.Sprintf("/workspaces/%s/audit_log_events", workspaceGID), query)
+}
+
+// GetEvents returns the events on a resource that happened since the sync
+// token was issued. When the sync token is missing or expired, Asana responds
+// with a fresh one only, which is returned within a *SyncTokenError.
+func (c *apiClient) GetEvents(ctx context.Context, resourceGID, syncToken string) (EventsResponse, error) {
+	query := make(url.Values)
+	query.Set("resource", resourceGID)
+	if syncToken != "" {
+		query.Set("sync", syncToken)
+	}
+
+	var preconditionFailed *EventsResponse
+	resp, err := fetch[EventsResponse](ctx, c, "/events", query,
+		angler.WithStatusHandler(http.StatusPreconditionFailed, handleErrorStatusWithResponse(&preconditionFailed, "sync token expired")),
+	)
+	if preconditionFailed != nil {
+		return resp, &SyncTokenError{Sync: preconditionFailed.Sync}
 	}
-	if len(errResp.Errors) != 0 {
-		return nil, nil, fmt.Errorf("bad HTTP response status response: %+v", errResp)
+	if err != nil {
+		return resp, err
 	}
 
-	return users.Data, users.NextPage, nil
+	return resp, nil
+}
+
+func (c *apiClient) GetTask(ctx context.Context, taskGID string, query url.Values) (Task, error) {
+	return fetchOne[Task](ctx, c, fmt.Sprintf("/tasks/%s", taskGID), query)
+}
+
+func (c *apiClient) GetProject(ctx context.Context, projectGID string, query url.Values) (Project, error) {
+	return fetchOne[Project](ctx, c, fmt.Sprintf("/projects/%s", projectGID), query)
+}
+
+// SearchTasks returns a single window of the workspace tasks matching the
+// query; see TaskSearch for building the query, and paging through the results.
+func (c *apiClient) SearchTasks(ctx context.Context, workspaceGID string, query url.Values) ([]Task, error) {
+	tasks, _, err := fetchList[Task](ctx, c, fmt.Sprintf("/workspaces/%s/tasks/search", workspaceGID), query)
+
+	return tasks, err
 }
 
-func (c *apiClient) ListWorkspaceUsers(workspaceId string, query url.Values) ([]User, *NextPage, error) {
-	var errResp *ErrorsResponse
-	handleStatusError := func(message string) angler.StatusHandlerFunc {
-		return handleErrorStatusWithResponse(&errResp, message)
+// Batch sends independent GET requests through the batch API, split into
+// batches of up to 10 requests, and returns their results in the same order.
+// The requests failed on their own are sent again according to the client
+// retry policy, along with the other ones left to retry, once the longest of
+// their delays elapsed.
+func (c *apiClient) Batch(ctx context.Context, reqs []BatchRequest) ([]BatchResult, error) {
+	results := make([]BatchResult, len(reqs))
+
+	// pending holds the indexes of the requests left to send
+	pending := make([]int, len(reqs))
+	for i := range reqs {
+		pending[i] = i
 	}
-	users, err := slumber.Retry(func() (MultipleResponse[User], error) {
 
-		users, err := angler.Fetch[MultipleResponse[User]](
-			angler.WithURL(fmt.Sprintf("%s/workspaces/%s/users?%s", c.host, workspaceId, query.Encode())),
-			angler.WithHeader("Authorization", fmt.Sprintf("Bearer %s", c.accessToken)),
-			angler.WithStatusHandler(http.StatusTooManyRequests, handleStatusTooManyRequests(50*time.Millisecond)),
-			angler.WithStatusHandler(http.StatusBadRequest, handleStatusError("missing of malformed parameter")),
-			angler.WithStatusHandler(http.StatusUnauthorized, handleStatusError("unauthorized")),
-			angler.WithStatusHandler(http.StatusNotFound, handleStatusError("not found")),
-			angler.WithStatusHandler(http.StatusInternalServerError, handleStatusError("internal error, try again later")),
+	for attempt := 1; len(pending) > 0; attempt++ {
+		var (
+			retried []int
+			delay   time.Duration
 		)
-		if err != nil && errors.Is(err, errToManyRequests) {
-			return users, err
+		for chunk := range slices.Chunk(pending, maxBatchActions) {
+			actions := make([]batchAction, 0, len(chunk))
+			for _, i := range chunk {
+				actions = append(actions, newBatchAction(reqs[i]))
+			}
+
+			chunkResults, err := fetchOne[[]BatchResult](withRequestCost(ctx, true, len(actions)), c, "/batch", nil,
+				angler.WithMethod(http.MethodPost),
+				angler.WithBody(DataRequest[batchBody]{Data: batchBody{Actions: actions}}),
+			)
+			if err != nil {
+				return nil, err
+			}
+			if len(chunkResults) != len(chunk) {
+				return nil, fmt.Errorf("batch responded with %d results for %d requests", len(chunkResults), len(chunk))
+			}
+
+			for j, i := range chunk {
+				results[i] = chunkResults[j]
+
+				resultErr := batchResultError(chunkResults[j], reqs[i].RelativePath)
+				if resultErr == nil {
+					continue
+				}
+				if resultErr.StatusCode == http.StatusTooManyRequests && resultErr.RetryAfter > 0 {
+					c.limiter.pause(resultErr.RetryAfter)
+				}
+				if resultDelay, retryable := c.retryPolicy.next(attempt, resultErr, true); retryable {
+					retried = append(retried, i)
+					delay = max(delay, resultDelay)
+				}
+			}
+		}
+
+		pending = retried
+		if len(pending) > 0 {
+			err := sleepContext(ctx, delay)
+			if err != nil {
+				return nil, err
+			}
 		}
-		return users, nil
-	},
-		slumber.WithRetryPolicy(slumber.ExponentialBackoff),
-		slumber.WithDelay(50*time.Millisecond),
-		slumber.WithRetries(5),
-	)
-	if err != nil {
-		return nil, nil, err
 	}
-	if errResp != nil {
-		return nil, nil, fmt.Errorf("bad HTTP response status response: %+v", errResp)
+
+	return results, nil
+}
+
+// CreateWebhook registers a webhook; Asana completes the handshake with the
+// webhook target before responding, so the target must already be served.
+func (c *apiClient) CreateWebhook(ctx context.Context, webhook WebhookRequest) (Webhook, error) {
+	return fetchOne[Webhook](ctx, c, "/webhooks", nil,
+		angler.WithMethod(http.MethodPost),
+		angler.WithBody(DataRequest[WebhookRequest]{Data: webhook}),
+	)
+}
+
+// DownloadAttachment fetches the content of an attachment, failing with
+// ErrAttachmentTooLarge if it exceeds maxSize bytes. The download URL is a
+// short lived, pre-signed URL, so the access token is not sent along.
+func (c *apiClient) DownloadAttachment(ctx context.Context, downloadURL string, maxSize int64) (*Download, error) {
+	download, attempts, err := retry(ctx, c.retryPolicy, func() (*Download, bool, error) {
+		download, err := downloadAttachment(ctx, c.httpClient, downloadURL, maxSize)
+		return download, true, err
+	})
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Retries = attempts - 1
 	}
 
-	return users.Data, users.NextPage, nil
+	return download, err
 }
 
-func (c *apiClient) ListWorkspaces(query url.Values) ([]Workspace, *NextPage, error) {
-	var errResp *ErrorsResponse
-	handleStatusError := func(message string) angler.StatusHandlerFunc {
-		return handleErrorStatusWithResponse(&errResp, message)
+func downloadAttachment(ctx context.Context, client *http.Client, downloadURL string, maxSize int64) (*Download, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	workspaces, err := slumber.Retry(func() (MultipleResponse[Workspace], error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-		workspaces, err := angler.Fetch[MultipleResponse[Workspace]](
-			angler.WithURL(fmt.Sprintf("%s/workspaces?%s", c.host, query.Encode())),
-			angler.WithHeader("Authorization", fmt.Sprintf("Bearer %s", c.accessToken)),
-			angler.WithStatusHandler(http.StatusTooManyRequests, handleStatusTooManyRequests(50*time.Millisecond)),
-			angler.WithStatusHandler(http.StatusBadRequest, handleStatusError("missing of malformed parameter")),
-			angler.WithStatusHandler(http.StatusUnauthorized, handleStatusError("unauthorized")),
-			angler.WithStatusHandler(http.StatusNotFound, handleStatusError("not found")),
-			angler.WithStatusHandler(http.StatusInternalServerError, handleStatusError("internal error, try again later")),
-		)
-		if err != nil && errors.Is(err, errToManyRequests) {
-			return workspaces, err
-		}
-		return workspaces, nil
-	},
-		slumber.WithRetryPolicy(slumber.ExponentialBackoff),
-		slumber.WithDelay(50*time.Millisecond),
-		slumber.WithRetries(5),
-	)
+	if resp.StatusCode != http.StatusOK {
+		// the query of the pre-signed URL holds its signature, so it is left
+		// out of the error
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, &APIError{StatusCode: resp.StatusCode, Path: req.URL.Path, RetryAfter: retryAfter}
+	}
+	if resp.ContentLength > maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	if errResp != nil {
-		return nil, nil, fmt.Errorf("bad HTTP response status response: %+v", errResp)
+	if int64(len(data)) > maxSize {
+		return nil, ErrAttachmentTooLarge
 	}
 
-	return workspaces.Data, workspaces.NextPage, nil
+	return &Download{
+		ContentType: resp.Header.Get("Content-Type"),
+		Data:        data,
+	}, nil
 }
 
-func (c *apiClient) ListProjects(query url.Values) ([]Project, *NextPage, error) {
-	var errResp *ErrorsResponse
-	handleStatusError := func(message string) angler.StatusHandlerFunc {
-		return handleErrorStatusWithResponse(&errResp, message)
-	}
-	projects, err := slumber.Retry(func() (MultipleResponse[Project], error) {
+// fetchOne requests a single resource, unwrapping it from the response envelope.
+func fetchOne[T any](ctx context.Context, c *apiClient, path string, query url.Values, options ...angler.RequestOption) (T, error) {
+	resp, err := fetch[SingleResponse[T]](ctx, c, path, query, options...)
 
-		projects, err := angler.Fetch[MultipleResponse[Project]](
-			angler.WithURL(fmt.Sprintf("%s/projects?%s", c.host, query.Encode())),
-			angler.WithHeader("Authorization", fmt.Sprintf("Bearer %s", c.accessToken)),
-			angler.WithStatusHandler(http.StatusTooManyRequests, handleStatusTooManyRequests(50*time.Millisecond)),
-			angler.WithStatusHandler(http.StatusBadRequest, handleStatusError("missing of malformed parameter")),
-			angler.WithStatusHandler(http.StatusUnauthorized, handleStatusError("unauthorized")),
-			angler.WithStatusHandler(http.StatusNotFound, handleStatusError("not found")),
-			angler.WithStatusHandler(http.StatusInternalServerError, handleStatusError("internal error, try again later")),
-		)
+	return resp.Data, err
+}
 
-		if err != nil && errors.Is(err, errToManyRequests) {
-			return projects, err
-		}
-		return projects, nil
-	},
-		slumber.WithRetryPolicy(slumber.ExponentialBackoff),
-		slumber.WithDelay(50*time.Millisecond),
-		slumber.WithRetries(5),
-	)
+// fetchList requests a paginated collection and returns its page of data
+// along with the pointer to the next page, if any.
+func fetchList[T any](ctx context.Context, c *apiClient, path string, query url.Values) ([]T, *NextPage, error) {
+	resp, err := fetch[MultipleResponse[T]](ctx, c, path, query)
 	if err != nil {
 		return nil, nil, err
 	}
-	if errResp != nil {
-		return nil, nil, fmt.Errorf("bad HTTP response status response: %+v", errResp)
+
+	return resp.Data, resp.NextPage, nil
+}
+
+// fetch sends a request to the given API path, retrying it according to the
+// client retry policy, and until ctx is done. The options are applied after
+// the default ones, so they may override the GET method or the status
+// handlers.
+func fetch[RT any](ctx context.Context, c *apiClient, path string, query url.Values, options ...angler.RequestOption) (RT, error) {
+	resp, attempts, err := retry(ctx, c.retryPolicy, func() (RT, bool, error) {
+		client := &contextClient{ctx: ctx, client: limitedClient{c.limiter, c.httpClient}}
+		requestOptions := []angler.RequestOption{
+			angler.WithURL(fmt.Sprintf("%s%s?%s", c.host, path, query.Encode())),
+			angler.WithClient(client),
+		}
+		for key, value := range c.headers {
+			requestOptions = append(requestOptions, angler.WithHeader(key, value))
+		}
+		requestOptions = append(requestOptions,
+			angler.WithHeader("Authorization", fmt.Sprintf("Bearer %s", c.accessToken)),
+			angler.WithDefaultStatusHandler(handleErrorStatus[RT](path)),
+		)
+		requestOptions = append(requestOptions, options...)
+
+		resp, err := angler.Fetch[RT](requestOptions...)
+		return resp, costOf(ctx, client.method).read, err
+	})
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Retries = attempts - 1
 	}
 
-	return projects.Data, projects.NextPage, nil
+	return resp, err
+}
+
+// contextClient binds every outgoing request to ctx, since angler builds
+// requests without one, and keeps the method of the last one, which angler
+// does not tell either.
+type contextClient struct {
+	ctx    context.Context
+	client angler.HTTPClient
+	method string
+}
+
+func (c *contextClient) Do(req *http.Request) (*http.Response, error) {
+	c.method = req.Method
+	return c.client.Do(req.WithContext(c.ctx))
 }
 
 func handleErrorStatusWithResponse[T any](errorResponse *T, message string) angler.StatusHandlerFunc {
@@ -193,19 +450,47 @@ func handleErrorStatusWithResponse[T any](errorResponse *T, message string) angl
 	}
 }
 
-func handleStatusTooManyRequests(sleepTime time.Duration) angler.StatusHandlerFunc {
-	return func(resp *http.Response) (any, error) {
-		sleepIfRetryAfter(&resp.Header, sleepTime)
+// handleErrorStatus handles the statuses without a handler of their own,
+// turning them into an APIError with the errors Asana responded with.
+func handleErrorStatus[RT any](path string) angler.StatusHandlerFunc {
+	return func(r *http.Response) (any, error) {
+		var resp RT
+		if r.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
 
-		return nil, errToManyRequests
+		var errResp ErrorsResponse
+		respBody, err := io.ReadAll(r.Body)
+		if err == nil {
+			// the errors are only a description, so a malformed body still
+			// results into an APIError
+			_ = json.Unmarshal(respBody, &errResp)
+		}
+		retryAfter, _ := parseRetryAfter(r.Header.Get("Retry-After"))
+
+		return resp, &APIError{
+			StatusCode: r.StatusCode,
+			Path:       path,
+			RetryAfter: retryAfter,
+			Errors:     errResp.Errors,
+		}
 	}
 }
 
-func sleepIfRetryAfter(headers *http.Header, defaultDuration time.Duration) {
-	if retryAfter := headers.Get("Retry-After"); retryAfter != "" {
-		delaySeconds, err := strconv.Atoi(retryAfter)
-		if err == nil {
-			time.Sleep((time.Duration(delaySeconds) * time.Second) - defaultDuration)
-		}
+// sleepContext pauses for d, returning early with ctx's error if it is done
+// before d elapses.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
@@ -0,0 +1,174 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/CristianCurteanu/asana-extractor/pkg/storage"
+)
+
+// Archiver downloads the content of attachments into the storage, next to the
+// JSON snapshots.
+type Archiver interface {
+	ArchiveAttachments(ctx context.Context, attachments []Attachment) ([]Attachment, error)
+}
+
+// rejectedAttachmentsFile keeps the content types of the attachments not
+// allowed once downloaded, per attachment GID.
+const rejectedAttachmentsFile = "attachments/rejected.json"
+
+type archiver struct {
+	apiclient    APIClient
+	fs           storage.File
+	maxSize      int64
+	contentTypes []string
+
+	// rejected are the content types of the attachments not allowed once
+	// downloaded, per attachment GID; nil until loaded
+	rejected map[string]string
+}
+
+// NewArchiver creates an Archiver which stores only the attachments up to
+// maxSize bytes, whose content type matches one of contentTypes. Content types
+// may use a wildcard subtype, like `image/*`; no content types allow any of them.
+func NewArchiver(apiclient APIClient, fs storage.File, maxSize int64, contentTypes []string) Archiver {
+	return &archiver{
+		apiclient:    apiclient,
+		fs:           fs,
+		maxSize:      maxSize,
+		contentTypes: contentTypes,
+	}
+}
+
+// ArchiveAttachments stores the content of the attachments hosted by Asana,
+// and returns them with ArchivedAs set to their storage file. Attachments
+// archived by previous runs are not downloaded again, while the ones not
+// matching the size or content type limits are skipped. The content type of
+// the attachments rejected once downloaded is remembered, so that they are
+// not downloaded again until it is allowed.
+func (a *archiver) ArchiveAttachments(ctx context.Context, attachments []Attachment) ([]Attachment, error) {
+	if a.rejected == nil {
+		err := a.loadRejected()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, attachment := range attachments {
+		if attachment.DownloadURL == nil || *attachment.DownloadURL == "" {
+			continue
+		}
+		if attachment.Size != nil && *attachment.Size > a.maxSize {
+			log.Printf("skipping attachment %q, size %d exceeds %d bytes", attachment.GID, *attachment.Size, a.maxSize)
+			continue
+		}
+		if contentType := mime.TypeByExtension(path.Ext(attachment.Name)); contentType != "" && !a.allowed(contentType) {
+			log.Printf("skipping attachment %q, content type %q is not allowed", attachment.GID, contentType)
+			continue
+		}
+		if contentType, found := a.rejected[attachment.GID]; found && !a.allowed(contentType) {
+			log.Printf("skipping attachment %q, content type %q is not allowed", attachment.GID, contentType)
+			continue
+		}
+
+		file := attachmentFile(attachment)
+		exists, err := a.fs.Exists(file)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			attachments[i].ArchivedAs = file
+			continue
+		}
+
+		download, err := a.apiclient.DownloadAttachment(ctx, *attachment.DownloadURL, a.maxSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("failed to download attachment %q, err=%q", attachment.GID, err)
+			continue
+		}
+		if !a.allowed(download.ContentType) {
+			log.Printf("skipping attachment %q, content type %q is not allowed", attachment.GID, download.ContentType)
+			a.rejected[attachment.GID] = download.ContentType
+			err = a.saveRejected()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		err = a.fs.Store(file, download.Data)
+		if err != nil {
+			return nil, err
+		}
+		attachments[i].ArchivedAs = file
+	}
+
+	return attachments, nil
+}
+
+func (a *archiver) allowed(contentType string) bool {
+	if len(a.contentTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range a.contentTypes {
+		if allowed == mediaType {
+			return true
+		}
+		if prefix, found := strings.CutSuffix(allowed, "/*"); found && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *archiver) loadRejected() error {
+	data, err := a.fs.Read(rejectedAttachmentsFile)
+	if err != nil {
+		return err
+	}
+
+	rejected := make(map[string]string)
+	if data != nil {
+		err = json.Unmarshal(data, &rejected)
+		if err != nil {
+			return fmt.Errorf("malformed rejected attachments: %w", err)
+		}
+	}
+	a.rejected = rejected
+
+	return nil
+}
+
+func (a *archiver) saveRejected() error {
+	data, err := json.Marshal(a.rejected)
+	if err != nil {
+		return err
+	}
+
+	return a.fs.Store(rejectedAttachmentsFile, data)
+}
+
+// attachmentFile is the storage file of an attachment; it is keyed by the
+// attachment GID, as the names are not unique, and may contain path separators.
+func attachmentFile(attachment Attachment) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(attachment.Name)
+	if name == "" || name == "." || name == ".." {
+		name = "content"
+	}
+
+	return path.Join("attachments", attachment.GID, name)
+}
@@ -0,0 +1,211 @@
+package asana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/CristianCurteanu/asana-extractor/pkg/storage"
+)
+
+// AuditLogQuery filters the audit log events; the zero value selects all of them.
+type AuditLogQuery struct {
+	StartAt     time.Time
+	EndAt       time.Time
+	EventType   string
+	ActorType   string
+	ActorGID    string
+	ResourceGID string
+}
+
+func (q AuditLogQuery) values() url.Values {
+	query := make(url.Values)
+	query.Set("limit", "100")
+	if !q.StartAt.IsZero() {
+		query.Set("start_at", q.StartAt.UTC().Format(time.RFC3339))
+	}
+	if !q.EndAt.IsZero() {
+		query.Set("end_at", q.EndAt.UTC().Format(time.RFC3339))
+	}
+	if q.EventType != "" {
+		query.Set("event_type", q.EventType)
+	}
+	if q.ActorType != "" {
+		query.Set("actor_type", q.ActorType)
+	}
+	if q.ActorGID != "" {
+		query.Set("actor_gid", q.ActorGID)
+	}
+	if q.ResourceGID != "" {
+		query.Set("resource_gid", q.ResourceGID)
+	}
+
+	return query
+}
+
+// AuditLogSyncer appends the audit log events of an enterprise workspace to
+// the storage, picking up where the previous sync left off.
+type AuditLogSyncer interface {
+	Sync(ctx context.Context) error
+}
+
+type auditLogSyncer struct {
+	apiclient    APIClient
+	fs           storage.File
+	workspaceGID string
+	query        AuditLogQuery
+
+	loaded bool
+	state  auditLogState
+	// skip holds the events appended after the last stored state, which are
+	// returned again by the API when resuming from that state.
+	skip map[string]struct{}
+}
+
+// auditLogState is the cursor of the audit log, along with the number of
+// events appended up to it.
+type auditLogState struct {
+	Offset string `json:"offset"`
+	Events int    `json:"events"`
+}
+
+// NewAuditLogSyncer creates an AuditLogSyncer, which stores the events into
+// the `audit_log/<workspace gid>.jsonl` file, one JSON event per line. The
+// query filters must not change between runs, as Asana rejects cursors used
+// with different filters.
+func NewAuditLogSyncer(apiclient APIClient, fs storage.File, workspaceGID string, query AuditLogQuery) AuditLogSyncer {
+	return &auditLogSyncer{
+		apiclient:    apiclient,
+		fs:           fs,
+		workspaceGID: workspaceGID,
+		query:        query,
+	}
+}
+
+func (a *auditLogSyncer) eventsFile() string {
+	return fmt.Sprintf("audit_log/%s.jsonl", a.workspaceGID)
+}
+
+func (a *auditLogSyncer) stateFile() string {
+	return fmt.Sprintf("audit_log/%s.state.json", a.workspaceGID)
+}
+
+// Sync fetches the events following the stored cursor, page by page. Each
+// page is appended before the cursor moves past it, so no events are lost if
+// the sync is interrupted, while the events appended right before an
+// interruption are skipped when they are fetched again. Asana always returns
+// a cursor for the audit log, even when there are no more events, so the sync
+// ends on the first empty page, and the next one continues from it. A page
+// without a cursor ends the sync as well, and is fetched again by the next
+// one.
+func (a *auditLogSyncer) Sync(ctx context.Context) error {
+	if !a.loaded {
+		err := a.load()
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		query := a.query.values()
+		if a.state.Offset != "" {
+			query.Set("offset", a.state.Offset)
+		}
+
+		events, nextPage, err := a.apiclient.ListAuditLogEvents(ctx, a.workspaceGID, query)
+		if err != nil {
+			return err
+		}
+
+		var appended []AuditLogEvent
+		for _, event := range events {
+			if _, found := a.skip[event.GID]; !found {
+				appended = append(appended, event)
+			}
+		}
+		err = storage.AppendJSONLines(a.fs, a.eventsFile(), appended)
+		if err != nil {
+			return err
+		}
+
+		a.state.Events += len(appended)
+		if nextPage == nil || nextPage.Offset == "" {
+			// the cursor stays before the page, which is fetched again by the
+			// next sync, so its events are skipped then, like the ones appended
+			// right before an interruption
+			if a.skip == nil {
+				a.skip = make(map[string]struct{})
+			}
+			for _, event := range events {
+				a.skip[event.GID] = struct{}{}
+			}
+			return nil
+		}
+
+		a.state.Offset = nextPage.Offset
+		err = a.saveState()
+		if err != nil {
+			return err
+		}
+		a.skip = nil
+
+		if len(events) == 0 {
+			return nil
+		}
+	}
+}
+
+func (a *auditLogSyncer) load() error {
+	data, err := a.fs.Read(a.stateFile())
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		err = json.Unmarshal(data, &a.state)
+		if err != nil {
+			return fmt.Errorf("malformed audit log state: %w", err)
+		}
+	}
+
+	events, err := a.fs.Read(a.eventsFile())
+	if err != nil {
+		return err
+	}
+
+	a.skip = make(map[string]struct{})
+	stored := 0
+	for line := range bytes.Lines(events) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		stored++
+		if stored <= a.state.Events {
+			continue
+		}
+
+		var event AuditLogEvent
+		err = json.Unmarshal(line, &event)
+		if err != nil {
+			return fmt.Errorf("malformed audit log event: %w", err)
+		}
+		a.skip[event.GID] = struct{}{}
+	}
+	a.state.Events = stored
+	a.loaded = true
+
+	return nil
+}
+
+func (a *auditLogSyncer) saveState() error {
+	data, err := json.Marshal(a.state)
+	if err != nil {
+		return err
+	}
+
+	return a.fs.Store(a.stateFile(), data)
+}
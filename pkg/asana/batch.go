@@ -0,0 +1,160 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBatchActions is the most actions Asana accepts in a single batch request.
+const maxBatchActions = 10
+
+// BatchRequest is a GET request sent through the batch API.
+type BatchRequest struct {
+	RelativePath string
+	Query        url.Values
+}
+
+// BatchResult is the response of a single request of a batch; Body holds the
+// whole response envelope.
+type BatchResult struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers"`
+	Body       json.RawMessage   `json:"body"`
+}
+
+type batchAction struct {
+	RelativePath string            `json:"relative_path"`
+	Method       string            `json:"method"`
+	Data         map[string]string `json:"data,omitempty"`
+	Options      *batchOptions     `json:"options,omitempty"`
+}
+
+type batchOptions struct {
+	Limit  int      `json:"limit,omitempty"`
+	Offset string   `json:"offset,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+type batchBody struct {
+	Actions []batchAction `json:"actions"`
+}
+
+// newBatchAction translates a request, as the batch API takes the pagination
+// and opt_fields parameters as options, and the other parameters as data.
+func newBatchAction(req BatchRequest) batchAction {
+	action := batchAction{
+		RelativePath: req.RelativePath,
+		Method:       "get",
+	}
+
+	var options batchOptions
+	for key, values := range req.Query {
+		if len(values) == 0 {
+			continue
+		}
+		switch key {
+		case "limit":
+			options.Limit, _ = strconv.Atoi(values[0])
+		case "offset":
+			options.Offset = values[0]
+		case "opt_fields":
+			options.Fields = strings.Split(values[0], ",")
+		default:
+			if action.Data == nil {
+				action.Data = make(map[string]string)
+			}
+			action.Data[key] = values[0]
+		}
+	}
+	if options.Limit != 0 || options.Offset != "" || len(options.Fields) != 0 {
+		action.Options = &options
+	}
+
+	return action
+}
+
+// batchResultError returns the APIError of a failed batch result, or nil if
+// it succeeded.
+func batchResultError(result BatchResult, path string) *APIError {
+	if result.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var errResp ErrorsResponse
+	_ = json.Unmarshal(result.Body, &errResp)
+
+	var retryAfter time.Duration
+	for key, value := range result.Headers {
+		if strings.EqualFold(key, "Retry-After") {
+			retryAfter, _ = parseRetryAfter(value)
+		}
+	}
+
+	return &APIError{StatusCode: result.StatusCode, Path: path, RetryAfter: retryAfter, Errors: errResp.Errors}
+}
+
+// decodeBatchList decodes the page of a collection out of a batch result.
+func decodeBatchList[T any](result BatchResult, path string) ([]T, *NextPage, error) {
+	if err := batchResultError(result, path); err != nil {
+		return nil, nil, err
+	}
+
+	var resp MultipleResponse[T]
+	err := json.Unmarshal(result.Body, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp.Data, resp.NextPage, nil
+}
+
+// collectBatched fetches whole collections through the batch API, following
+// their pages, and returns the items of each request, in the same order.
+func collectBatched[T any](ctx context.Context, apiclient APIClient, reqs []BatchRequest) ([][]T, error) {
+	res := make([][]T, len(reqs))
+
+	// pending holds the indexes of the requests with pages left to fetch
+	pending := make([]int, len(reqs))
+	queries := make([]url.Values, len(reqs))
+	for i, req := range reqs {
+		pending[i] = i
+		queries[i] = make(url.Values, len(req.Query))
+		for key, values := range req.Query {
+			queries[i][key] = values
+		}
+	}
+
+	for len(pending) > 0 {
+		batch := make([]BatchRequest, 0, len(pending))
+		for _, i := range pending {
+			batch = append(batch, BatchRequest{RelativePath: reqs[i].RelativePath, Query: queries[i]})
+		}
+
+		results, err := apiclient.Batch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []int
+		for j, i := range pending {
+			items, nextPage, err := decodeBatchList[T](results[j], reqs[i].RelativePath)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = append(res[i], items...)
+
+			if nextPage != nil && nextPage.Offset != "" {
+				queries[i].Set("offset", nextPage.Offset)
+				next = append(next, i)
+			}
+		}
+		pending = next
+	}
+
+	return res, nil
+}
@@ -1,10 +1,32 @@
 package asana
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type MultipleResponse[T any] struct {
 	Data     []T       `json:"data"`
 	NextPage *NextPage `json:"next_page,omitempty"`
 }
 
+type SingleResponse[T any] struct {
+	Data T `json:"data"`
+}
+
+// EventsResponse holds the events on a resource, along with the sync token
+// to request the following ones.
+type EventsResponse struct {
+	Data    []Event `json:"data"`
+	Sync    string  `json:"sync"`
+	HasMore bool    `json:"has_more"`
+}
+
+// DataRequest wraps the body of the requests sending data to Asana.
+type DataRequest[T any] struct {
+	Data T `json:"data"`
+}
+
 type NextPage struct {
 	Offset string `json:"offset"`
 }
@@ -15,22 +37,474 @@ type User struct {
 	Email      string    `json:"email"`
 	Workspaces []Compact `json:"workspaces"`
 	Photo      *Photo    `json:"photo,omitempty"`
+	// WorkspaceMemberships tell whether the user is an admin, a guest or
+	// deactivated, in each of their workspaces.
+	WorkspaceMemberships []WorkspaceMembership `json:"workspace_memberships"`
+}
+
+type WorkspaceMembership struct {
+	GID           string         `json:"gid"`
+	User          Compact        `json:"user"`
+	Workspace     Compact        `json:"workspace"`
+	IsAdmin       bool           `json:"is_admin"`
+	IsGuest       bool           `json:"is_guest"`
+	IsActive      bool           `json:"is_active"`
+	VacationDates *VacationDates `json:"vacation_dates"`
+	CreatedAt     *time.Time     `json:"created_at"`
+}
+
+type VacationDates struct {
+	StartOn string  `json:"start_on"`
+	EndOn   *string `json:"end_on"`
 }
 
 type Workspace struct {
-	GID string `json:"gid"`
+	GID            string   `json:"gid"`
+	Name           string   `json:"name,omitempty"`
+	IsOrganization bool     `json:"is_organization"`
+	EmailDomains   []string `json:"email_domains,omitempty"`
 }
 
 type Project struct {
-	GID string `json:"gid"`
+	GID                 string              `json:"gid"`
+	Name                string              `json:"name,omitempty"`
+	Owner               *Compact            `json:"owner,omitempty"`
+	Team                *Compact            `json:"team,omitempty"`
+	Archived            bool                `json:"archived"`
+	Color               string              `json:"color,omitempty"`
+	CreatedAt           *time.Time          `json:"created_at,omitempty"`
+	ModifiedAt          *time.Time          `json:"modified_at,omitempty"`
+	StartOn             string              `json:"start_on,omitempty"`
+	DueOn               string              `json:"due_on,omitempty"`
+	CurrentStatusUpdate *StatusUpdate       `json:"current_status_update,omitempty"`
+	PrivacySetting      string              `json:"privacy_setting"`
+	Public              bool                `json:"public"`
+	Memberships         []ProjectMembership `json:"memberships,omitempty"`
+}
+
+// ProjectMembership grants a user or a team, as told by the member resource
+// type, access to a project.
+type ProjectMembership struct {
+	GID         string  `json:"gid"`
+	Member      Compact `json:"member"`
+	AccessLevel string  `json:"access_level"`
+}
+
+type Task struct {
+	GID          string             `json:"gid"`
+	Name         string             `json:"name"`
+	Assignee     *Compact           `json:"assignee"`
+	Completed    bool               `json:"completed"`
+	CompletedAt  *time.Time         `json:"completed_at"`
+	StartOn      string             `json:"start_on"`
+	DueOn        string             `json:"due_on"`
+	DueAt        *time.Time         `json:"due_at"`
+	CreatedAt    *time.Time         `json:"created_at"`
+	ModifiedAt   *time.Time         `json:"modified_at"`
+	Memberships  []TaskMembership   `json:"memberships"`
+	Parent       *Compact           `json:"parent"`
+	NumSubtasks  int                `json:"num_subtasks"`
+	Tags         []Compact          `json:"tags"`
+	CustomFields []CustomFieldValue `json:"custom_fields"`
+}
+
+// CustomField returns the value of the task custom field with the given
+// name, or nil if the task has no such custom field.
+func (t Task) CustomField(name string) *CustomFieldValue {
+	for i := range t.CustomFields {
+		if t.CustomFields[i].Name == name {
+			return &t.CustomFields[i]
+		}
+	}
+
+	return nil
+}
+
+type TaskMembership struct {
+	Project Compact  `json:"project"`
+	Section *Compact `json:"section,omitempty"`
+}
+
+// SectionIn returns the section in which the task is placed within the given
+// project, or nil if the task is not a member of it.
+func (t Task) SectionIn(projectGID string) *Compact {
+	for _, membership := range t.Memberships {
+		if membership.Project.GID == projectGID {
+			return membership.Section
+		}
+	}
+
+	return nil
+}
+
+type Section struct {
+	GID       string     `json:"gid"`
+	Name      string     `json:"name"`
+	Project   *Compact   `json:"project,omitempty"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+type Team struct {
+	GID          string           `json:"gid"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	Organization *Compact         `json:"organization,omitempty"`
+	Memberships  []TeamMembership `json:"memberships"`
+}
+
+// TeamMembership links a user to a team; the user GID matches the one of
+// the User records.
+type TeamMembership struct {
+	GID             string  `json:"gid"`
+	User            Compact `json:"user"`
+	Team            Compact `json:"team"`
+	IsAdmin         bool    `json:"is_admin"`
+	IsGuest         bool    `json:"is_guest"`
+	IsLimitedAccess bool    `json:"is_limited_access"`
+}
+
+type Portfolio struct {
+	GID       string     `json:"gid"`
+	Name      string     `json:"name"`
+	Color     string     `json:"color"`
+	Owner     *Compact   `json:"owner"`
+	Workspace *Compact   `json:"workspace,omitempty"`
+	CreatedAt *time.Time `json:"created_at"`
+	// Items are the projects and the nested portfolios of the portfolio,
+	// distinguished by their resource type.
+	Items []Compact `json:"items"`
+}
+
+type Goal struct {
+	GID        string      `json:"gid"`
+	Name       string      `json:"name"`
+	Notes      string      `json:"notes"`
+	Status     *string     `json:"status"`
+	StartOn    string      `json:"start_on"`
+	DueOn      string      `json:"due_on"`
+	Owner      *Compact    `json:"owner"`
+	Workspace  *Compact    `json:"workspace,omitempty"`
+	TimePeriod *TimePeriod `json:"time_period"`
+	Metric     *GoalMetric `json:"metric"`
+	// Supporting are the goals, projects and portfolios contributing to the goal.
+	Supporting []GoalRelationship `json:"supporting_relationships"`
+}
+
+type TimePeriod struct {
+	GID         string `json:"gid"`
+	DisplayName string `json:"display_name"`
+	Period      string `json:"period"`
+	StartOn     string `json:"start_on"`
+	EndOn       string `json:"end_on"`
+}
+
+type GoalMetric struct {
+	GID                 string   `json:"gid"`
+	ResourceSubtype     string   `json:"resource_subtype"`
+	Unit                string   `json:"unit"`
+	Precision           int      `json:"precision"`
+	CurrencyCode        *string  `json:"currency_code"`
+	InitialNumberValue  float64  `json:"initial_number_value"`
+	TargetNumberValue   float64  `json:"target_number_value"`
+	CurrentNumberValue  *float64 `json:"current_number_value"`
+	CurrentDisplayValue string   `json:"current_display_value"`
+	ProgressSource      string   `json:"progress_source"`
+}
+
+type GoalRelationship struct {
+	GID                string  `json:"gid"`
+	SupportedGoal      Compact `json:"supported_goal"`
+	SupportingResource Compact `json:"supporting_resource"`
+	ContributionWeight float64 `json:"contribution_weight"`
+}
+
+// GoalTree holds the goals of a workspace, starting from the top level ones.
+type GoalTree struct {
+	Workspace Compact    `json:"workspace"`
+	Goals     []GoalNode `json:"goals"`
+}
+
+// GoalNode is a goal along with the goals supporting it.
+type GoalNode struct {
+	Goal
+	Subgoals []GoalNode `json:"subgoals"`
+}
+
+// Story is an entry in the activity log of a task: either a comment, or a
+// system event like an assignment, a section move or a custom field change,
+// as told by its resource subtype.
+type Story struct {
+	GID             string      `json:"gid"`
+	Type            string      `json:"type"`
+	ResourceSubtype string      `json:"resource_subtype"`
+	Text            string      `json:"text"`
+	CreatedAt       *time.Time  `json:"created_at"`
+	CreatedBy       *Compact    `json:"created_by"`
+	Target          *Compact    `json:"target,omitempty"`
+	Assignee        *Compact    `json:"assignee,omitempty"`
+	OldSection      *Compact    `json:"old_section,omitempty"`
+	NewSection      *Compact    `json:"new_section,omitempty"`
+	CustomField     *Compact    `json:"custom_field,omitempty"`
+	OldEnumValue    *Compact    `json:"old_enum_value,omitempty"`
+	NewEnumValue    *Compact    `json:"new_enum_value,omitempty"`
+	OldTextValue    *string     `json:"old_text_value,omitempty"`
+	NewTextValue    *string     `json:"new_text_value,omitempty"`
+	OldNumberValue  *float64    `json:"old_number_value,omitempty"`
+	NewNumberValue  *float64    `json:"new_number_value,omitempty"`
+	OldDates        *StoryDates `json:"old_dates,omitempty"`
+	NewDates        *StoryDates `json:"new_dates,omitempty"`
+}
+
+type StoryDates struct {
+	StartOn *string `json:"start_on"`
+	DueOn   *string `json:"due_on"`
+	DueAt   *string `json:"due_at"`
 }
 
+const (
+	StoryTypeComment = "comment"
+	StoryTypeSystem  = "system"
+)
+
+type Attachment struct {
+	GID             string     `json:"gid"`
+	Name            string     `json:"name"`
+	ResourceSubtype string     `json:"resource_subtype"`
+	Host            string     `json:"host"`
+	Size            *int64     `json:"size"`
+	DownloadURL     *string    `json:"download_url"`
+	PermanentURL    string     `json:"permanent_url"`
+	ViewURL         *string    `json:"view_url"`
+	CreatedAt       *time.Time `json:"created_at"`
+	Parent          *Compact   `json:"parent"`
+	// ArchivedAs is the storage file the attachment content was archived to,
+	// if any.
+	ArchivedAs string `json:"archived_as,omitempty"`
+}
+
+type Tag struct {
+	GID       string     `json:"gid"`
+	Name      string     `json:"name"`
+	Color     *string    `json:"color"`
+	Notes     string     `json:"notes"`
+	Workspace *Compact   `json:"workspace,omitempty"`
+	CreatedAt *time.Time `json:"created_at"`
+	// Tasks are the tasks labeled with the tag, across all projects.
+	Tasks []Compact `json:"tasks"`
+}
+
+type TimeTrackingEntry struct {
+	GID             string     `json:"gid"`
+	DurationMinutes int        `json:"duration_minutes"`
+	EnteredOn       string     `json:"entered_on"`
+	CreatedAt       *time.Time `json:"created_at"`
+	CreatedBy       *Compact   `json:"created_by"`
+	Task            *Compact   `json:"task,omitempty"`
+	// AttributableTo is the project the logged time is billed to.
+	AttributableTo *Compact `json:"attributable_to,omitempty"`
+}
+
+// TimeTrackingReport holds all the time tracking entries, along with their
+// durations summed up per project and per user.
+type TimeTrackingReport struct {
+	Entries   []TimeTrackingEntry `json:"entries"`
+	ByProject []TimeTrackingTotal `json:"by_project"`
+	ByUser    []TimeTrackingTotal `json:"by_user"`
+}
+
+type TimeTrackingTotal struct {
+	Compact
+	DurationMinutes int `json:"duration_minutes"`
+}
+
+// StatusUpdate is a status update posted on a project, portfolio or goal.
+type StatusUpdate struct {
+	GID             string     `json:"gid"`
+	Title           string     `json:"title"`
+	Text            string     `json:"text"`
+	StatusType      string     `json:"status_type"`
+	ResourceSubtype string     `json:"resource_subtype"`
+	Author          *Compact   `json:"author"`
+	CreatedBy       *Compact   `json:"created_by"`
+	CreatedAt       *time.Time `json:"created_at"`
+	ModifiedAt      *time.Time `json:"modified_at"`
+	Parent          *Compact   `json:"parent"`
+}
+
+// Color returns the color Asana shows for the status type, or an empty
+// string for the status types without one.
+func (s StatusUpdate) Color() string {
+	switch s.StatusType {
+	case "on_track", "achieved", "complete":
+		return "green"
+	case "at_risk", "partial":
+		return "yellow"
+	case "off_track", "missed":
+		return "red"
+	case "on_hold":
+		return "blue"
+	default:
+		return ""
+	}
+}
+
+type AuditLogEvent struct {
+	GID           string                `json:"gid"`
+	CreatedAt     *time.Time            `json:"created_at"`
+	EventType     string                `json:"event_type"`
+	EventCategory string                `json:"event_category"`
+	Actor         AuditLogEventActor    `json:"actor"`
+	Resource      AuditLogEventResource `json:"resource"`
+	Context       AuditLogEventContext  `json:"context"`
+	Details       map[string]any        `json:"details"`
+}
+
+type AuditLogEventActor struct {
+	GID       string `json:"gid,omitempty"`
+	ActorType string `json:"actor_type"`
+	Name      string `json:"name,omitempty"`
+	Email     string `json:"email,omitempty"`
+}
+
+type AuditLogEventResource struct {
+	GID             string `json:"gid"`
+	ResourceType    string `json:"resource_type"`
+	ResourceSubtype string `json:"resource_subtype,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Email           string `json:"email,omitempty"`
+}
+
+type AuditLogEventContext struct {
+	ContextType             string `json:"context_type"`
+	APIAuthenticationMethod string `json:"api_authentication_method,omitempty"`
+	ClientIPAddress         string `json:"client_ip_address,omitempty"`
+	UserAgent               string `json:"user_agent,omitempty"`
+	OAuthAppName            string `json:"oauth_app_name,omitempty"`
+}
+
+// Event is a change of a resource, reported by the Events API.
+type Event struct {
+	Action    string       `json:"action"`
+	User      *Compact     `json:"user"`
+	Resource  Compact      `json:"resource"`
+	Parent    *Compact     `json:"parent"`
+	CreatedAt *time.Time   `json:"created_at"`
+	Change    *EventChange `json:"change,omitempty"`
+}
+
+type EventChange struct {
+	Field        string          `json:"field"`
+	Action       string          `json:"action"`
+	NewValue     json.RawMessage `json:"new_value,omitempty"`
+	AddedValue   json.RawMessage `json:"added_value,omitempty"`
+	RemovedValue json.RawMessage `json:"removed_value,omitempty"`
+}
+
+type Webhook struct {
+	GID           string          `json:"gid"`
+	Resource      Compact         `json:"resource"`
+	Target        string          `json:"target"`
+	Active        bool            `json:"active"`
+	CreatedAt     *time.Time      `json:"created_at"`
+	LastSuccessAt *time.Time      `json:"last_success_at"`
+	LastFailureAt *time.Time      `json:"last_failure_at"`
+	Filters       []WebhookFilter `json:"filters,omitempty"`
+}
+
+type WebhookFilter struct {
+	ResourceType    string   `json:"resource_type,omitempty"`
+	ResourceSubtype string   `json:"resource_subtype,omitempty"`
+	Action          string   `json:"action,omitempty"`
+	Fields          []string `json:"fields,omitempty"`
+}
+
+// WebhookRequest is the body of a webhook registration.
+type WebhookRequest struct {
+	Resource string          `json:"resource"`
+	Target   string          `json:"target"`
+	Filters  []WebhookFilter `json:"filters,omitempty"`
+}
+
+// WebhookDelivery is the body of the requests Asana sends to a webhook target.
+type WebhookDelivery struct {
+	Events []Event `json:"events"`
+}
+
+// CustomField is the definition of a custom field; which of its properties are
+// set depends on its resource subtype.
+type CustomField struct {
+	GID                 string       `json:"gid"`
+	Name                string       `json:"name"`
+	Description         string       `json:"description"`
+	ResourceSubtype     string       `json:"resource_subtype"`
+	Format              string       `json:"format,omitempty"`
+	Precision           *int         `json:"precision,omitempty"`
+	CurrencyCode        *string      `json:"currency_code,omitempty"`
+	EnumOptions         []EnumOption `json:"enum_options,omitempty"`
+	IsGlobalToWorkspace bool         `json:"is_global_to_workspace"`
+	CreatedBy           *Compact     `json:"created_by,omitempty"`
+}
+
+// CustomFieldSetting is the attachment of a custom field to a project.
+type CustomFieldSetting struct {
+	GID         string      `json:"gid"`
+	IsImportant bool        `json:"is_important"`
+	Project     *Compact    `json:"project"`
+	CustomField CustomField `json:"custom_field"`
+}
+
+// CustomFieldValue is the value of a custom field on a task. Only the value
+// property matching the resource subtype is set, while DisplayValue holds the
+// value formatted as shown in Asana.
+type CustomFieldValue struct {
+	GID             string       `json:"gid"`
+	Name            string       `json:"name"`
+	ResourceSubtype string       `json:"resource_subtype"`
+	DisplayValue    *string      `json:"display_value"`
+	Precision       *int         `json:"precision,omitempty"`
+	TextValue       *string      `json:"text_value,omitempty"`
+	NumberValue     *float64     `json:"number_value,omitempty"`
+	EnumValue       *EnumOption  `json:"enum_value,omitempty"`
+	MultiEnumValues []EnumOption `json:"multi_enum_values,omitempty"`
+	DateValue       *DateValue   `json:"date_value,omitempty"`
+	PeopleValue     []Compact    `json:"people_value,omitempty"`
+}
+
+type EnumOption struct {
+	GID     string `json:"gid"`
+	Name    string `json:"name"`
+	Color   string `json:"color,omitempty"`
+	Enabled bool   `json:"enabled"`
+}
+
+type DateValue struct {
+	Date     string     `json:"date"`
+	DateTime *time.Time `json:"date_time"`
+}
+
+const (
+	CustomFieldText      = "text"
+	CustomFieldNumber    = "number"
+	CustomFieldEnum      = "enum"
+	CustomFieldMultiEnum = "multi_enum"
+	CustomFieldDate      = "date"
+	CustomFieldPeople    = "people"
+)
+
 type Compact struct {
 	GID          string `json:"gid"`
 	ResourceType string `json:"resource_type"`
 	Name         string `json:"name"`
 }
 
+const (
+	ResourceTypeWorkspace = "workspace"
+	ResourceTypeProject   = "project"
+	ResourceTypePortfolio = "portfolio"
+	ResourceTypeGoal      = "goal"
+	ResourceTypeTask      = "task"
+)
+
 type Photo struct {
 	Small  string `json:"photo.image_27x27"`
 	Medium string `json:"photo.image_128x128"`
@@ -0,0 +1,71 @@
+package asana
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIError is returned when Asana responds with an error status, carrying the
+// errors Asana describes it with.
+type APIError struct {
+	StatusCode int
+	Path       string
+	Retries    int
+	// RetryAfter is how long Asana asked to wait before retrying, if it did.
+	RetryAfter time.Duration
+	Errors     []ErrorResponse
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "asana responded %d %s to %q", e.StatusCode, http.StatusText(e.StatusCode), e.Path)
+	if e.Retries > 0 {
+		fmt.Fprintf(&b, " after %d retries", e.Retries)
+	}
+
+	for i, errResp := range e.Errors {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(errResp.Message)
+		if errResp.Help != "" {
+			fmt.Fprintf(&b, " (%s)", errResp.Help)
+		}
+	}
+
+	return b.String()
+}
+
+// IsUnauthorized tells whether err is an APIError for a missing, invalid or
+// revoked access token.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden tells whether err is an APIError for a resource the user is not
+// allowed to access.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsNotFound tells whether err is an APIError for a missing resource.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsPaymentRequired tells whether err is an APIError for a feature the
+// workspace plan does not include.
+func IsPaymentRequired(err error) bool {
+	return hasStatus(err, http.StatusPaymentRequired)
+}
+
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
@@ -0,0 +1,244 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+
+	"github.com/CristianCurteanu/asana-extractor/pkg/storage"
+)
+
+const (
+	eventsFile     = "events.jsonl"
+	syncTokensFile = "events_sync_tokens.json"
+)
+
+// EventsConsumer follows the changes of the projects through the Events API,
+// in between full crawls.
+type EventsConsumer interface {
+	Sync(ctx context.Context) error
+}
+
+type eventsConsumer struct {
+	apiclient APIClient
+	extractor Extractor
+	fs        storage.File
+	fullCrawl func(ctx context.Context) error
+
+	// tokens are the sync tokens per resource GID; nil until loaded
+	tokens map[string]string
+}
+
+// NewEventsConsumer creates an EventsConsumer, which appends the events to
+// the `events.jsonl` file, one JSON event per line, and keeps the sync tokens
+// in the storage, so that it resumes from them after a restart. fullCrawl is
+// expected to store full snapshots, and runs whenever the events are not
+// enough to follow the changes.
+func NewEventsConsumer(apiclient APIClient, extractor Extractor, fs storage.File, fullCrawl func(ctx context.Context) error) EventsConsumer {
+	return &eventsConsumer{
+		apiclient: apiclient,
+		extractor: extractor,
+		fs:        fs,
+		fullCrawl: fullCrawl,
+	}
+}
+
+// Sync stores the events of every project. The full crawl runs on the first
+// sync, and whenever the sync token of any project expired, as the events in
+// between are lost. The projects created since the last sync are followed
+// from now on, while the removed ones are not followed anymore.
+func (c *eventsConsumer) Sync(ctx context.Context) error {
+	if c.tokens == nil {
+		err := c.loadTokens()
+		if err != nil {
+			return err
+		}
+	}
+	if len(c.tokens) == 0 {
+		return c.crawl(ctx)
+	}
+
+	err := c.follow(ctx)
+	if err != nil {
+		return err
+	}
+
+	expired := false
+	for _, resource := range slices.Sorted(maps.Keys(c.tokens)) {
+		events, token, err := c.poll(ctx, resource, c.tokens[resource])
+		var syncTokenErr *SyncTokenError
+		if errors.As(err, &syncTokenErr) {
+			// the expired token is kept until the crawl succeeds, so that a
+			// failed crawl is run again by the next sync
+			expired = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = storage.AppendJSONLines(c.fs, eventsFile, events)
+		if err != nil {
+			return err
+		}
+		c.tokens[resource] = token
+		err = c.saveTokens()
+		if err != nil {
+			return err
+		}
+	}
+
+	if expired {
+		return c.crawl(ctx)
+	}
+
+	return nil
+}
+
+// poll fetches all the events on a resource since the sync token, returning
+// them along with the token to use next time.
+func (c *eventsConsumer) poll(ctx context.Context, resource, token string) ([]Event, string, error) {
+	var events []Event
+	for {
+		resp, err := c.apiclient.GetEvents(ctx, resource, token)
+		if err != nil {
+			return nil, "", err
+		}
+		events = append(events, resp.Data...)
+		token = resp.Sync
+
+		if !resp.HasMore {
+			return events, token, nil
+		}
+	}
+}
+
+// follow issues the sync tokens of the projects created since the last sync,
+// and drops the ones of the projects removed since.
+func (c *eventsConsumer) follow(ctx context.Context) error {
+	projects, err := c.listProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, project := range projects {
+		if _, found := c.tokens[project]; found {
+			continue
+		}
+
+		c.tokens[project], err = c.issueToken(ctx, project)
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+	for project := range c.tokens {
+		if !slices.Contains(projects, project) {
+			delete(c.tokens, project)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return c.saveTokens()
+}
+
+// crawl runs the full crawl, and follows the projects it found from now on.
+// The sync tokens are issued before the crawl, so that the changes made while
+// crawling are not lost.
+func (c *eventsConsumer) crawl(ctx context.Context) error {
+	projects, err := c.listProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]string, len(projects))
+	for _, project := range projects {
+		tokens[project], err = c.issueToken(ctx, project)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.fullCrawl(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.tokens = tokens
+	return c.saveTokens()
+}
+
+// listProjects returns the GIDs of the projects of every workspace, listed
+// compact, as only their GIDs are needed.
+func (c *eventsConsumer) listProjects(ctx context.Context) ([]string, error) {
+	workspaces, err := c.extractor.GetAllWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []string
+	for _, ws := range workspaces {
+		query := make(url.Values)
+		query.Set("limit", "100")
+		query.Set("workspace", ws.GID)
+
+		for project, err := range Paginate(ctx, c.apiclient.ListProjects, query) {
+			if err != nil {
+				return nil, err
+			}
+			projects = append(projects, project.GID)
+		}
+	}
+
+	return projects, nil
+}
+
+// issueToken returns a sync token of the project, following its events from
+// now on.
+func (c *eventsConsumer) issueToken(ctx context.Context, project string) (string, error) {
+	resp, err := c.apiclient.GetEvents(ctx, project, "")
+	var syncTokenErr *SyncTokenError
+	switch {
+	case errors.As(err, &syncTokenErr):
+		return syncTokenErr.Sync, nil
+	case err != nil:
+		return "", fmt.Errorf("unable to get the sync token of %q project: %w", project, err)
+	default:
+		return resp.Sync, nil
+	}
+}
+
+func (c *eventsConsumer) loadTokens() error {
+	data, err := c.fs.Read(syncTokensFile)
+	if err != nil {
+		return err
+	}
+
+	c.tokens = make(map[string]string)
+	if data == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(data, &c.tokens)
+	if err != nil {
+		return fmt.Errorf("malformed sync tokens: %w", err)
+	}
+	return nil
+}
+
+func (c *eventsConsumer) saveTokens() error {
+	data, err := json.Marshal(c.tokens)
+	if err != nil {
+		return err
+	}
+
+	return c.fs.Store(syncTokensFile, data)
+}
@@ -1,106 +1,858 @@
 package asana
 
-import "net/url"
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taskFields are the opt_fields requested for the tasks with the rich
+// profile, since by default Asana returns only the compact task
+// representation.
+var taskFields = []string{
+	"name",
+	"assignee.name",
+	"completed",
+	"completed_at",
+	"start_on",
+	"due_on",
+	"due_at",
+	"created_at",
+	"modified_at",
+	"memberships.project.name",
+	"memberships.section.name",
+	"parent.name",
+	"tags.name",
+	"custom_fields.name",
+	"custom_fields.resource_subtype",
+	"custom_fields.display_value",
+	"custom_fields.precision",
+	"custom_fields.text_value",
+	"custom_fields.number_value",
+	"custom_fields.enum_value.name",
+	"custom_fields.enum_value.color",
+	"custom_fields.enum_value.enabled",
+	"custom_fields.multi_enum_values.name",
+	"custom_fields.multi_enum_values.color",
+	"custom_fields.multi_enum_values.enabled",
+	"custom_fields.date_value",
+	"custom_fields.people_value.name",
+}
+
+// projectFields are the opt_fields requested for the projects with the rich
+// profile.
+var projectFields = []string{
+	"name",
+	"owner.name",
+	"team.name",
+	"archived",
+	"color",
+	"created_at",
+	"modified_at",
+	"start_on",
+	"due_on",
+	"current_status_update.title",
+	"current_status_update.status_type",
+	"current_status_update.created_at",
+	"privacy_setting",
+	"public",
+}
+
+var customFieldFields = []string{
+	"name",
+	"description",
+	"resource_subtype",
+	"format",
+	"precision",
+	"currency_code",
+	"enum_options.name",
+	"enum_options.color",
+	"enum_options.enabled",
+	"is_global_to_workspace",
+	"created_by.name",
+}
+
+var storyFields = []string{
+	"type",
+	"resource_subtype",
+	"text",
+	"created_at",
+	"created_by.name",
+	"target.name",
+	"assignee.name",
+	"old_section.name",
+	"new_section.name",
+	"custom_field.name",
+	"old_enum_value.name",
+	"new_enum_value.name",
+	"old_text_value",
+	"new_text_value",
+	"old_number_value",
+	"new_number_value",
+	"old_dates",
+	"new_dates",
+}
+
+var goalFields = []string{
+	"name",
+	"notes",
+	"status",
+	"start_on",
+	"due_on",
+	"owner.name",
+	"workspace.name",
+	"time_period.display_name",
+	"time_period.period",
+	"time_period.start_on",
+	"time_period.end_on",
+	"metric.resource_subtype",
+	"metric.unit",
+	"metric.precision",
+	"metric.currency_code",
+	"metric.initial_number_value",
+	"metric.target_number_value",
+	"metric.current_number_value",
+	"metric.current_display_value",
+	"metric.progress_source",
+}
 
 type Extractor interface {
-	GetAllUsers() ([]User, error)
-	GetAllProjects() ([]Project, error)
+	GetAllWorkspaces(ctx context.Context) ([]Workspace, error)
+	GetAllUsers(ctx context.Context) ([]User, error)
+	GetAllProjects(ctx context.Context) ([]Project, error)
+	GetAllTasks(ctx context.Context) ([]Task, error)
+	GetAllSections(ctx context.Context) ([]Section, error)
+	GetAllTeams(ctx context.Context) ([]Team, error)
+	GetAllPortfolios(ctx context.Context) ([]Portfolio, error)
+	GetAllGoals(ctx context.Context) ([]GoalTree, error)
+	GetAllStories(ctx context.Context) ([]Story, error)
+	GetAllAttachments(ctx context.Context) ([]Attachment, error)
+	GetAllCustomFields(ctx context.Context) ([]CustomField, error)
+	GetAllCustomFieldSettings(ctx context.Context) ([]CustomFieldSetting, error)
+	GetAllTags(ctx context.Context) ([]Tag, error)
+	GetTimeTrackingReport(ctx context.Context) (TimeTrackingReport, error)
+	GetAllStatusUpdates(ctx context.Context) ([]StatusUpdate, error)
+	SearchTasks(ctx context.Context, search *TaskSearch) ([]Task, error)
 }
 
 type extractor struct {
 	apiclient APIClient
+	profiles  FieldProfiles
 }
 
-func NewExtractor(apiclient APIClient) Extractor {
-	return &extractor{apiclient}
+// NewExtractor builds an extractor, which extracts the workspaces, projects
+// and tasks with the fields of their profiles; a nil profiles map selects the
+// rich profile for all of them.
+func NewExtractor(apiclient APIClient, profiles FieldProfiles) Extractor {
+	return &extractor{apiclient, profiles}
 }
 
-func (e extractor) defaultQuery() url.Values {
+// defaultQuery returns the query of a page of results, along with the
+// opt_fields to request, if any; otherwise Asana returns the compact
+// representation of the resources.
+func (e extractor) defaultQuery(fields ...string) url.Values {
 	var query url.Values = make(url.Values)
 	query.Set("limit", "100")
+	if len(fields) > 0 {
+		query.Set("opt_fields", strings.Join(fields, ","))
+	}
 
 	return query
 }
 
-func (e extractor) GetAllWorkspaces() ([]Workspace, error) {
-	query := e.defaultQuery()
-	workspaces, nextPage, err := e.apiclient.ListWorkspaces(query)
+// workspaceQuery returns the default query, scoped to a single workspace.
+func (e extractor) workspaceQuery(workspaceGID string, fields ...string) url.Values {
+	query := e.defaultQuery(fields...)
+	query.Set("workspace", workspaceGID)
+
+	return query
+}
+
+// GetAllWorkspaces fetches the workspaces and organizations the user is a
+// member of.
+func (e extractor) GetAllWorkspaces(ctx context.Context) ([]Workspace, error) {
+	query := e.defaultQuery(e.profiles.fields(ResourceTypeWorkspace)...)
+
+	return Collect(Paginate(ctx, e.apiclient.ListWorkspaces, query))
+}
+
+// GetAllUsers fetches the users of every workspace, joined with their
+// workspace memberships. Users belonging to multiple workspaces are returned
+// once, listing all of their workspaces; the members not listed as users of a
+// workspace are returned as well, as told by their membership.
+func (e extractor) GetAllUsers(ctx context.Context) ([]User, error) {
+	workspaces, err := e.GetAllWorkspaces(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if nextPage != nil {
-		for {
-			additionalWorkspaces, nextPage, err := e.apiclient.ListWorkspaces(query)
+	membershipsQuery := e.defaultQuery("user.name", "workspace.name", "is_admin", "is_guest", "is_active", "vacation_dates", "created_at")
+
+	var usersRes []User = make([]User, 0, len(workspaces)*100*5)
+	usersIndex := make(map[string]int)
+	addUser := func(user User, ws Workspace) {
+		i, found := usersIndex[user.GID]
+		if !found {
+			user.Workspaces = nil
+			i = len(usersRes)
+			usersIndex[user.GID] = i
+			usersRes = append(usersRes, user)
+		}
+		usersRes[i].Workspaces = append(usersRes[i].Workspaces, Compact{GID: ws.GID, ResourceType: ResourceTypeWorkspace, Name: ws.Name})
+	}
+	for _, ws := range workspaces {
+		var memberships []WorkspaceMembership
+		for membership, err := range Paginate(ctx, listOf(e.apiclient.ListWorkspaceMemberships, ws.GID), membershipsQuery) {
 			if err != nil {
 				return nil, err
 			}
+			memberships = append(memberships, membership)
+		}
 
-			workspaces = append(workspaces, additionalWorkspaces...)
+		listed := make(map[string]struct{})
+		for user, err := range Paginate(ctx, e.apiclient.ListUsers, e.workspaceQuery(ws.GID)) {
+			if err != nil {
+				return nil, err
+			}
+			listed[user.GID] = struct{}{}
+			addUser(user, ws)
+		}
 
-			if nextPage == nil {
-				break
-			} else {
-				query.Set("offset", nextPage.Offset)
+		for _, membership := range memberships {
+			// the members missing from the users of the workspace, like the
+			// deactivated ones, are built from their membership
+			if _, found := listed[membership.User.GID]; !found {
+				listed[membership.User.GID] = struct{}{}
+				addUser(User{GID: membership.User.GID, Name: membership.User.Name}, ws)
 			}
+
+			i := usersIndex[membership.User.GID]
+			usersRes[i].WorkspaceMemberships = append(usersRes[i].WorkspaceMemberships, membership)
 		}
 	}
 
-	return workspaces, nil
+	return usersRes, nil
 }
 
-func (e extractor) GetAllUsers() ([]User, error) {
-	workspaces, err := e.GetAllWorkspaces()
+// listProjects fetches the projects of every workspace with the given fields,
+// without their memberships; the other extractions traverse the compact
+// projects.
+func (e extractor) listProjects(ctx context.Context, fields ...string) ([]Project, error) {
+	workspaces, err := e.GetAllWorkspaces(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	query := e.defaultQuery()
-	var usersRes []User = make([]User, 0, len(workspaces)*100*5)
+	projectsRes := make([]Project, 0, len(workspaces)*100*5)
 	for _, ws := range workspaces {
-		for {
-			query.Set("workspace", ws.GID)
-			users, nextPage, err := e.apiclient.ListUsers(query)
+		query := e.workspaceQuery(ws.GID, fields...)
+
+		for project, err := range Paginate(ctx, e.apiclient.ListProjects, query) {
 			if err != nil {
 				return nil, err
 			}
-			usersRes = append(usersRes, users...)
+			projectsRes = append(projectsRes, project)
+		}
+	}
 
-			if nextPage == nil {
-				break
-			} else {
-				query.Set("offset", nextPage.Offset)
+	return projectsRes, nil
+}
+
+// GetAllProjects fetches the projects of every workspace, along with the
+// users and teams having access to each of them.
+func (e extractor) GetAllProjects(ctx context.Context) ([]Project, error) {
+	projects, err := e.listProjects(ctx, e.profiles.fields(ResourceTypeProject)...)
+	if err != nil {
+		return nil, err
+	}
+
+	reqs := make([]BatchRequest, 0, len(projects))
+	for _, project := range projects {
+		query := e.defaultQuery("member.name", "member.resource_type", "access_level")
+		query.Set("parent", project.GID)
+		reqs = append(reqs, BatchRequest{RelativePath: "/memberships", Query: query})
+	}
+
+	memberships, err := collectBatched[ProjectMembership](ctx, e.apiclient, reqs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range projects {
+		projects[i].Memberships = memberships[i]
+	}
+
+	return projects, nil
+}
+
+// GetAllTasks fetches the tasks of every project, along with their subtasks
+// at any depth. Tasks that belong to multiple projects are returned only
+// once; all of their projects are listed in the task memberships.
+func (e extractor) GetAllTasks(ctx context.Context) ([]Task, error) {
+	projects, err := e.listProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the subtasks are walked only for the tasks having some, whatever the
+	// profile
+	fields := withField(e.profiles.fields(ResourceTypeTask), "num_subtasks")
+
+	seen := make(map[string]struct{})
+	tasksRes := make([]Task, 0, len(projects)*100)
+	var parents []Task
+	for _, project := range projects {
+		query := e.defaultQuery(fields...)
+		query.Set("project", project.GID)
+
+		for task, err := range Paginate(ctx, e.apiclient.ListTasks, query) {
+			if err != nil {
+				return nil, err
+			}
+			if _, found := seen[task.GID]; found {
+				continue
+			}
+			seen[task.GID] = struct{}{}
+			tasksRes = append(tasksRes, task)
+			if task.NumSubtasks > 0 {
+				parents = append(parents, task)
 			}
 		}
 	}
 
-	return usersRes, nil
+	// subtasks are not listed along with the project tasks, unless they are
+	// added to the project too, so they are fetched level by level
+	for len(parents) > 0 {
+		reqs := make([]BatchRequest, 0, len(parents))
+		for _, parent := range parents {
+			reqs = append(reqs, BatchRequest{RelativePath: "/tasks/" + parent.GID + "/subtasks", Query: e.defaultQuery(fields...)})
+		}
+
+		subtasks, err := collectBatched[Task](ctx, e.apiclient, reqs)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []Task
+		for i, parent := range parents {
+			for _, task := range subtasks[i] {
+				if _, found := seen[task.GID]; found {
+					continue
+				}
+				seen[task.GID] = struct{}{}
+				if task.Parent == nil {
+					task.Parent = &Compact{GID: parent.GID, ResourceType: ResourceTypeTask, Name: parent.Name}
+				}
+				tasksRes = append(tasksRes, task)
+				if task.NumSubtasks > 0 {
+					next = append(next, task)
+				}
+			}
+		}
+		parents = next
+	}
+
+	return tasksRes, nil
 }
 
-func (e extractor) GetAllProjects() ([]Project, error) {
-	workspaces, err := e.GetAllWorkspaces()
+// GetAllSections fetches the sections of every project, which together with
+// the task memberships describe the board columns the tasks are placed in.
+func (e extractor) GetAllSections(ctx context.Context) ([]Section, error) {
+	projects, err := e.listProjects(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	query := e.defaultQuery()
-	projectsRes := make([]Project, 0, len(workspaces)*100*5)
+	query := e.defaultQuery("name", "project.name", "created_at")
+
+	reqs := make([]BatchRequest, 0, len(projects))
+	for _, project := range projects {
+		reqs = append(reqs, BatchRequest{RelativePath: "/projects/" + project.GID + "/sections", Query: query})
+	}
+
+	sections, err := collectBatched[Section](ctx, e.apiclient, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	sectionsRes := make([]Section, 0, len(projects)*10)
+	for _, projectSections := range sections {
+		sectionsRes = append(sectionsRes, projectSections...)
+	}
+
+	return sectionsRes, nil
+}
+
+// GetAllTeams fetches the teams of every organization, along with their
+// members. Plain workspaces have no teams, so they are skipped.
+func (e extractor) GetAllTeams(ctx context.Context) ([]Team, error) {
+	workspaces, err := e.GetAllWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	teamsQuery := e.defaultQuery("name", "description", "organization.name")
+
+	membershipsQuery := e.defaultQuery("user.name", "team.name", "is_admin", "is_guest", "is_limited_access")
+
+	var teamsRes []Team
 	for _, ws := range workspaces {
-		for {
-			query.Set("workspace", ws.GID)
-			projects, nextPage, err := e.apiclient.ListProjects(query)
+		if !ws.IsOrganization {
+			continue
+		}
+
+		for team, err := range Paginate(ctx, listOf(e.apiclient.ListTeams, ws.GID), teamsQuery) {
 			if err != nil {
 				return nil, err
 			}
-			projectsRes = append(projectsRes, projects...)
 
-			if nextPage == nil {
-				break
-			} else {
-				query.Set("offset", nextPage.Offset)
+			team.Memberships, err = Collect(Paginate(ctx, listOf(e.apiclient.ListTeamMemberships, team.GID), membershipsQuery))
+			if err != nil {
+				return nil, err
 			}
+			teamsRes = append(teamsRes, team)
 		}
 	}
 
-	return projectsRes, nil
+	return teamsRes, nil
+}
+
+// GetAllPortfolios fetches the portfolios of every workspace along with their
+// items. Portfolios nested into other portfolios are fetched as well, even if
+// they are not listed in the workspace, and every portfolio is returned once.
+func (e extractor) GetAllPortfolios(ctx context.Context) ([]Portfolio, error) {
+	workspaces, err := e.GetAllWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolioFields := []string{"name", "color", "owner.name", "workspace.name", "created_at"}
+	itemsQuery := e.defaultQuery("name", "resource_type")
+
+	var (
+		portfoliosRes []Portfolio
+		pending       []Portfolio
+		seen          = make(map[string]struct{})
+	)
+	for _, ws := range workspaces {
+		query := e.workspaceQuery(ws.GID, portfolioFields...)
+
+		workspacePortfolios, err := Collect(Paginate(ctx, e.apiclient.ListPortfolios, query))
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, workspacePortfolios...)
+	}
+
+	for len(pending) > 0 {
+		portfolio := pending[0]
+		pending = pending[1:]
+		if _, found := seen[portfolio.GID]; found {
+			continue
+		}
+		seen[portfolio.GID] = struct{}{}
+
+		portfolio.Items, err = Collect(Paginate(ctx, listOf(e.apiclient.ListPortfolioItems, portfolio.GID), itemsQuery))
+		if err != nil {
+			return nil, err
+		}
+		portfoliosRes = append(portfoliosRes, portfolio)
+
+		for _, item := range portfolio.Items {
+			if item.ResourceType != ResourceTypePortfolio {
+				continue
+			}
+			if _, found := seen[item.GID]; found {
+				continue
+			}
+
+			query := make(url.Values)
+			query.Set("opt_fields", strings.Join(portfolioFields, ","))
+			nested, err := e.apiclient.GetPortfolio(ctx, item.GID, query)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, nested)
+		}
+	}
+
+	return portfoliosRes, nil
+}
+
+// GetAllGoals fetches the goals of every workspace, and arranges them into a
+// tree, following the supporting relationships between them.
+func (e extractor) GetAllGoals(ctx context.Context) ([]GoalTree, error) {
+	workspaces, err := e.GetAllWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	relationshipsQuery := e.defaultQuery("supported_goal.name", "supporting_resource.name", "supporting_resource.resource_type", "contribution_weight")
+
+	treesRes := make([]GoalTree, 0, len(workspaces))
+	for _, ws := range workspaces {
+		query := e.workspaceQuery(ws.GID, goalFields...)
+
+		goals, err := Collect(Paginate(ctx, e.apiclient.ListGoals, query))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range goals {
+			relationshipsQuery.Set("supported_goal", goals[i].GID)
+			goals[i].Supporting, err = Collect(Paginate(ctx, e.apiclient.ListGoalRelationships, relationshipsQuery))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		treesRes = append(treesRes, GoalTree{
+			Workspace: Compact{GID: ws.GID, ResourceType: ResourceTypeWorkspace, Name: ws.Name},
+			Goals:     buildGoalTree(goals),
+		})
+	}
+
+	return treesRes, nil
+}
+
+// buildGoalTree nests every goal under the goals it supports. The goals that
+// do not support any other goal are the roots of the tree; a goal supporting
+// several goals shows up under each of them.
+func buildGoalTree(goals []Goal) []GoalNode {
+	byGID := make(map[string]Goal, len(goals))
+	for _, goal := range goals {
+		byGID[goal.GID] = goal
+	}
+
+	isSubgoal := make(map[string]bool)
+	for _, goal := range goals {
+		for _, rel := range goal.Supporting {
+			if _, found := byGID[rel.SupportingResource.GID]; found && rel.SupportingResource.ResourceType == ResourceTypeGoal {
+				isSubgoal[rel.SupportingResource.GID] = true
+			}
+		}
+	}
+
+	visited := make(map[string]bool)
+	inPath := make(map[string]bool)
+	var build func(goal Goal) GoalNode
+	build = func(goal Goal) GoalNode {
+		visited[goal.GID] = true
+		inPath[goal.GID] = true
+		defer delete(inPath, goal.GID)
+
+		node := GoalNode{Goal: goal}
+		for _, rel := range goal.Supporting {
+			subgoal, found := byGID[rel.SupportingResource.GID]
+			if !found || rel.SupportingResource.ResourceType != ResourceTypeGoal || inPath[subgoal.GID] {
+				continue
+			}
+			node.Subgoals = append(node.Subgoals, build(subgoal))
+		}
+
+		return node
+	}
+
+	var roots []GoalNode
+	for _, goal := range goals {
+		if !isSubgoal[goal.GID] {
+			roots = append(roots, build(goal))
+		}
+	}
+	// goals supporting each other in a cycle have no root, so they are added
+	// as roots themselves, to not lose them from the snapshot.
+	for _, goal := range goals {
+		if !visited[goal.GID] {
+			roots = append(roots, build(goal))
+		}
+	}
+
+	return roots
+}
+
+// GetAllStories fetches the comments and the activity log of every task.
+func (e extractor) GetAllStories(ctx context.Context) ([]Story, error) {
+	tasks, err := e.GetAllTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := e.defaultQuery(storyFields...)
+
+	reqs := make([]BatchRequest, 0, len(tasks))
+	for _, task := range tasks {
+		reqs = append(reqs, BatchRequest{RelativePath: "/tasks/" + task.GID + "/stories", Query: query})
+	}
+
+	stories, err := collectBatched[Story](ctx, e.apiclient, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	storiesRes := make([]Story, 0, len(tasks)*5)
+	for _, taskStories := range stories {
+		storiesRes = append(storiesRes, taskStories...)
+	}
+
+	return storiesRes, nil
+}
+
+// GetAllAttachments fetches the metadata of the attachments of every task.
+func (e extractor) GetAllAttachments(ctx context.Context) ([]Attachment, error) {
+	tasks, err := e.GetAllTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := e.defaultQuery("name", "resource_subtype", "host", "size", "download_url", "permanent_url", "view_url", "created_at", "parent.name")
+
+	var attachmentsRes []Attachment
+	for _, task := range tasks {
+		query.Set("parent", task.GID)
+		for attachment, err := range Paginate(ctx, e.apiclient.ListAttachments, query) {
+			if err != nil {
+				return nil, err
+			}
+			attachmentsRes = append(attachmentsRes, attachment)
+		}
+	}
+
+	return attachmentsRes, nil
+}
+
+// GetAllCustomFields fetches the custom field definitions of every workspace.
+func (e extractor) GetAllCustomFields(ctx context.Context) ([]CustomField, error) {
+	workspaces, err := e.GetAllWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := e.defaultQuery(customFieldFields...)
+
+	var customFieldsRes []CustomField
+	for _, ws := range workspaces {
+		for customField, err := range Paginate(ctx, listOf(e.apiclient.ListCustomFields, ws.GID), query) {
+			if err != nil {
+				return nil, err
+			}
+			customFieldsRes = append(customFieldsRes, customField)
+		}
+	}
+
+	return customFieldsRes, nil
+}
+
+// GetAllCustomFieldSettings fetches the custom fields attached to every project.
+func (e extractor) GetAllCustomFieldSettings(ctx context.Context) ([]CustomFieldSetting, error) {
+	projects, err := e.listProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{"is_important", "project.name"}
+	for _, field := range customFieldFields {
+		fields = append(fields, "custom_field."+field)
+	}
+	query := e.defaultQuery(fields...)
+
+	reqs := make([]BatchRequest, 0, len(projects))
+	for _, project := range projects {
+		reqs = append(reqs, BatchRequest{RelativePath: "/projects/" + project.GID + "/custom_field_settings", Query: query})
+	}
+
+	settings, err := collectBatched[CustomFieldSetting](ctx, e.apiclient, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Concat(settings...), nil
+}
+
+// GetAllTags fetches the tags of every workspace, along with the tasks
+// labeled with each of them.
+func (e extractor) GetAllTags(ctx context.Context) ([]Tag, error) {
+	workspaces, err := e.GetAllWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tagsQuery := e.defaultQuery("name", "color", "notes", "workspace.name", "created_at")
+
+	tasksQuery := e.defaultQuery("name")
+
+	var tagsRes []Tag
+	for _, ws := range workspaces {
+		for tag, err := range Paginate(ctx, listOf(e.apiclient.ListTags, ws.GID), tagsQuery) {
+			if err != nil {
+				return nil, err
+			}
+
+			tag.Tasks, err = Collect(Paginate(ctx, listOf(e.apiclient.ListTagTasks, tag.GID), tasksQuery))
+			if err != nil {
+				return nil, err
+			}
+			tagsRes = append(tagsRes, tag)
+		}
+	}
+
+	return tagsRes, nil
+}
+
+// GetTimeTrackingReport fetches the time tracking entries of every task, and
+// sums up their durations per project and per user. An entry is counted for
+// the project it is attributable to, or otherwise for the first project of
+// its task, so that time logged on tasks shared between projects is not
+// counted twice.
+func (e extractor) GetTimeTrackingReport(ctx context.Context) (TimeTrackingReport, error) {
+	var report TimeTrackingReport
+
+	tasks, err := e.GetAllTasks(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	query := e.defaultQuery("duration_minutes", "entered_on", "created_at", "created_by.name", "attributable_to.name")
+
+	byProject := newTimeTrackingTotals()
+	byUser := newTimeTrackingTotals()
+	for _, task := range tasks {
+		for entry, err := range Paginate(ctx, listOf(e.apiclient.ListTimeTrackingEntries, task.GID), query) {
+			if err != nil {
+				return report, err
+			}
+			entry.Task = &Compact{GID: task.GID, ResourceType: ResourceTypeTask, Name: task.Name}
+			report.Entries = append(report.Entries, entry)
+
+			if entry.AttributableTo != nil {
+				byProject.add(*entry.AttributableTo, entry.DurationMinutes)
+			} else if len(task.Memberships) > 0 {
+				byProject.add(task.Memberships[0].Project, entry.DurationMinutes)
+			}
+			if entry.CreatedBy != nil {
+				byUser.add(*entry.CreatedBy, entry.DurationMinutes)
+			}
+		}
+	}
+	report.ByProject = byProject.totals
+	report.ByUser = byUser.totals
+
+	return report, nil
+}
+
+// timeTrackingTotals sums up durations per resource, keeping the order in
+// which the resources were first seen.
+type timeTrackingTotals struct {
+	index  map[string]int
+	totals []TimeTrackingTotal
+}
+
+func newTimeTrackingTotals() *timeTrackingTotals {
+	return &timeTrackingTotals{index: make(map[string]int)}
+}
+
+func (t *timeTrackingTotals) add(resource Compact, minutes int) {
+	i, found := t.index[resource.GID]
+	if !found {
+		i = len(t.totals)
+		t.index[resource.GID] = i
+		t.totals = append(t.totals, TimeTrackingTotal{Compact: resource})
+	}
+	t.totals[i].DurationMinutes += minutes
+}
+
+// GetAllStatusUpdates fetches the status updates of every project and
+// portfolio, from the oldest to the most recent one; the ones without a
+// creation time come first.
+func (e extractor) GetAllStatusUpdates(ctx context.Context) ([]StatusUpdate, error) {
+	projects, err := e.listProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	portfolios, err := e.GetAllPortfolios(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make([]string, 0, len(projects)+len(portfolios))
+	for _, project := range projects {
+		parents = append(parents, project.GID)
+	}
+	for _, portfolio := range portfolios {
+		parents = append(parents, portfolio.GID)
+	}
+
+	query := e.defaultQuery("title", "text", "status_type", "resource_subtype", "author.name", "created_by.name", "created_at", "modified_at", "parent.name", "parent.resource_type")
+
+	var statusUpdatesRes []StatusUpdate
+	for _, parent := range parents {
+		query.Set("parent", parent)
+		for statusUpdate, err := range Paginate(ctx, e.apiclient.ListStatusUpdates, query) {
+			if err != nil {
+				return nil, err
+			}
+			statusUpdatesRes = append(statusUpdatesRes, statusUpdate)
+		}
+	}
+	slices.SortStableFunc(statusUpdatesRes, func(a, b StatusUpdate) int {
+		// the status updates without a creation time, if any, come first
+		switch {
+		case a.CreatedAt == nil && b.CreatedAt == nil:
+			return 0
+		case a.CreatedAt == nil:
+			return -1
+		case b.CreatedAt == nil:
+			return 1
+		}
+		return a.CreatedAt.Compare(*b.CreatedAt)
+	})
+
+	return statusUpdatesRes, nil
+}
+
+// SearchTasks returns the workspace tasks matching the search, going through
+// as many result windows as the search allows. Windows are bounded by the
+// creation time of the last task of the previous window, shifted by a
+// millisecond, so that tasks created at the same time are not skipped; the
+// tasks showing up twice are dropped.
+func (e extractor) SearchTasks(ctx context.Context, search *TaskSearch) ([]Task, error) {
+	query := search.Query()
+	query.Set("limit", strconv.Itoa(searchWindow))
+	// the windows are bounded by the creation time, whatever the profile
+	query.Set("opt_fields", strings.Join(withField(e.profiles.fields(ResourceTypeTask), "created_at"), ","))
+	if search.sortBy == "" {
+		// Asana sorts by modification time by default, which the result
+		// windows can not be bounded by
+		query.Set("sort_by", SearchSortByCreatedAt)
+		query.Set("sort_ascending", "false")
+	}
+
+	seen := make(map[string]struct{})
+	var tasksRes []Task
+	for {
+		tasks, err := e.apiclient.SearchTasks(ctx, search.workspaceGID, query)
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for _, task := range tasks {
+			if _, found := seen[task.GID]; found {
+				continue
+			}
+			seen[task.GID] = struct{}{}
+			tasksRes = append(tasksRes, task)
+			added++
+		}
+
+		if !search.pageable() || len(tasks) < searchWindow || added == 0 {
+			return tasksRes, nil
+		}
+		last := tasks[len(tasks)-1]
+		if last.CreatedAt == nil {
+			return tasksRes, nil
+		}
+		query.Set("created_at.before", last.CreatedAt.Add(time.Millisecond).UTC().Format(time.RFC3339Nano))
+	}
 }
@@ -0,0 +1,86 @@
+package asana
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FieldProfile selects how many fields of a resource are extracted: the lean
+// profile keeps the snapshots small, while the rich one holds everything the
+// DTOs can describe.
+type FieldProfile string
+
+const (
+	FieldProfileLean FieldProfile = "lean"
+	FieldProfileRich FieldProfile = "rich"
+)
+
+// FieldProfiles selects the field profile per resource type, like `project`;
+// the resource types missing from it are extracted with the rich profile.
+type FieldProfiles map[string]FieldProfile
+
+// ParseFieldProfiles parses a comma separated list of resource type and
+// profile pairs, like `project=lean,task=rich`.
+func ParseFieldProfiles(s string) (FieldProfiles, error) {
+	profiles := make(FieldProfiles)
+	if s == "" {
+		return profiles, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		resourceType, profile, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("malformed field profile %q, expected `<resource type>=<profile>`", pair)
+		}
+		if _, found := resourceFields[resourceType]; !found {
+			return nil, fmt.Errorf("unknown field profile resource type %q", resourceType)
+		}
+
+		switch FieldProfile(profile) {
+		case FieldProfileLean, FieldProfileRich:
+			profiles[resourceType] = FieldProfile(profile)
+		default:
+			return nil, fmt.Errorf("unknown field profile %q of %q", profile, resourceType)
+		}
+	}
+
+	return profiles, nil
+}
+
+// fields returns the opt_fields requested for the resource type.
+func (p FieldProfiles) fields(resourceType string) []string {
+	profile, found := p[resourceType]
+	if !found {
+		profile = FieldProfileRich
+	}
+
+	return resourceFields[resourceType][profile]
+}
+
+// withField returns the fields along with the given one, which the extraction
+// relies on whatever the profile.
+func withField(fields []string, field string) []string {
+	if slices.Contains(fields, field) {
+		return fields
+	}
+
+	return append(slices.Clone(fields), field)
+}
+
+// resourceFields are the opt_fields requested for every field profile of the
+// resource types which support them.
+var resourceFields = map[string]map[FieldProfile][]string{
+	ResourceTypeWorkspace: {
+		FieldProfileLean: {"name", "is_organization"},
+		FieldProfileRich: {"name", "is_organization", "email_domains"},
+	},
+	ResourceTypeProject: {
+		FieldProfileLean: {"name", "archived", "privacy_setting", "public"},
+		FieldProfileRich: projectFields,
+	},
+	ResourceTypeTask: {
+		FieldProfileLean: {"name", "assignee.name", "completed", "due_on", "memberships.project.name", "memberships.section.name"},
+		FieldProfileRich: taskFields,
+	},
+}
@@ -0,0 +1,163 @@
+package asana
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientOption configures the API client built by NewAPIClient.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	httpClient   *http.Client
+	transport    http.RoundTripper
+	proxyURL     *url.URL
+	rootCAs      *x509.CertPool
+	certificates []tls.Certificate
+	timeout      time.Duration
+	headers      map[string]string
+	retryPolicy  RetryPolicy
+
+	requestsPerMinute   int
+	maxConcurrentReads  int
+	maxConcurrentWrites int
+}
+
+// WithHTTPClient sends the requests through client, instead of
+// http.DefaultClient; the other transport options are applied on top of it.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *clientConfig) {
+		c.httpClient = client
+	}
+}
+
+// WithTransport sends the requests through transport.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *clientConfig) {
+		c.transport = transport
+	}
+}
+
+// WithProxy sends the requests through the proxy at proxyURL, instead of
+// the one set through the environment, if any.
+func WithProxy(proxyURL *url.URL) ClientOption {
+	return func(c *clientConfig) {
+		c.proxyURL = proxyURL
+	}
+}
+
+// WithRootCAs verifies the server certificates against rootCAs, instead of
+// the system ones.
+func WithRootCAs(rootCAs *x509.CertPool) ClientOption {
+	return func(c *clientConfig) {
+		c.rootCAs = rootCAs
+	}
+}
+
+// WithClientCertificates presents the certificates to the servers, or to
+// the proxy, asking for mutual TLS.
+func WithClientCertificates(certificates ...tls.Certificate) ClientOption {
+	return func(c *clientConfig) {
+		c.certificates = append(c.certificates, certificates...)
+	}
+}
+
+// WithTimeout bounds every attempt of a request, reading its response
+// included.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithUserAgent sends userAgent as the User-Agent of every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return WithHeader("User-Agent", userAgent)
+}
+
+// WithHeader sends the header along with every API request; the
+// Authorization header can not be overridden.
+func WithHeader(key, value string) ClientOption {
+	return func(c *clientConfig) {
+		if c.headers == nil {
+			c.headers = make(map[string]string)
+		}
+		c.headers[key] = value
+	}
+}
+
+// WithRetryPolicy retries the failed requests according to policy, instead
+// of DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *clientConfig) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithRateLimit keeps the requests within other quotas than the ones of the
+// Asana paid plans, like the 150 requests per minute of the free plan. All
+// the values must be positive.
+func WithRateLimit(requestsPerMinute, maxConcurrentReads, maxConcurrentWrites int) ClientOption {
+	return func(c *clientConfig) {
+		c.requestsPerMinute = requestsPerMinute
+		c.maxConcurrentReads = maxConcurrentReads
+		c.maxConcurrentWrites = maxConcurrentWrites
+	}
+}
+
+// client builds the HTTP client the options ask for. Without any transport
+// option, http.DefaultClient is used as is, so that the changes of
+// http.DefaultTransport are followed. The proxy and the TLS options can only
+// be applied on an *http.Transport, so any other transport is refused along
+// with them.
+func (c *clientConfig) client() (*http.Client, error) {
+	tlsConfigured := c.rootCAs != nil || len(c.certificates) > 0
+	if c.httpClient == nil && c.transport == nil && c.proxyURL == nil && !tlsConfigured && c.timeout == 0 {
+		return http.DefaultClient, nil
+	}
+
+	client := &http.Client{}
+	if c.httpClient != nil {
+		*client = *c.httpClient
+	}
+	if c.transport != nil {
+		client.Transport = c.transport
+	}
+	if c.timeout != 0 {
+		client.Timeout = c.timeout
+	}
+
+	if c.proxyURL != nil || tlsConfigured {
+		// the options are applied on a copy of the given transport, or of the
+		// default one
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		transport, ok := base.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("unable to set the proxy or the TLS options on a %T transport", base)
+		}
+		transport = transport.Clone()
+
+		if c.proxyURL != nil {
+			transport.Proxy = http.ProxyURL(c.proxyURL)
+		}
+		if tlsConfigured {
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{}
+			}
+			if c.rootCAs != nil {
+				transport.TLSClientConfig.RootCAs = c.rootCAs
+			}
+			transport.TLSClientConfig.Certificates = append(transport.TLSClientConfig.Certificates, c.certificates...)
+		}
+		client.Transport = transport
+	}
+
+	return client, nil
+}
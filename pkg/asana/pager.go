@@ -0,0 +1,67 @@
+package asana
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"net/url"
+)
+
+// ListFunc fetches a single page of a collection; the APIClient list methods
+// satisfy it directly.
+type ListFunc[T any] func(ctx context.Context, query url.Values) ([]T, *NextPage, error)
+
+// listOf binds a collection nested under a parent resource, like the sections
+// of a project, to the given parent, so that it can be paginated.
+func listOf[T any](list func(ctx context.Context, parentGID string, query url.Values) ([]T, *NextPage, error), parentGID string) ListFunc[T] {
+	return func(ctx context.Context, query url.Values) ([]T, *NextPage, error) {
+		return list(ctx, parentGID, query)
+	}
+}
+
+// Paginate exposes a paginated collection as an iterator, requesting the next
+// page only when the previous one is fully consumed. The query is copied, so the
+// offset of one iteration never leaks into the caller's query, or other iterations.
+// Iteration stops after the first error is yielded.
+func Paginate[T any](ctx context.Context, list ListFunc[T], query url.Values) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		query := maps.Clone(query)
+		if query == nil {
+			query = make(url.Values)
+		}
+		query.Del("offset")
+
+		for {
+			items, nextPage, err := list(ctx, query)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if nextPage == nil || nextPage.Offset == "" {
+				return
+			}
+			query.Set("offset", nextPage.Offset)
+		}
+	}
+}
+
+// Collect drains an iterator returned by Paginate into a slice.
+func Collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var res []T
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, item)
+	}
+
+	return res, nil
+}
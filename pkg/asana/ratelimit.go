@@ -0,0 +1,181 @@
+package asana
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/CristianCurteanu/angler"
+)
+
+// Asana quotas of the paid plans; see https://developers.asana.com/docs/rate-limits
+const (
+	requestsPerMinute   = 1500
+	maxConcurrentReads  = 50
+	maxConcurrentWrites = 15
+)
+
+// rateLimiter keeps the requests of an API client within the Asana quotas,
+// so that the jobs running in parallel do not hit them: a token bucket,
+// refilled at the per-minute quota, limits the rate of the requests, while
+// the reads and the writes have separate concurrency caps. Once Asana
+// throttles a request anyway, all of them are paused for as long as its
+// Retry-After tells.
+type rateLimiter struct {
+	mu          sync.Mutex
+	tokens      float64
+	capacity    float64
+	refill      time.Duration
+	updatedAt   time.Time
+	pausedUntil time.Time
+
+	reads  chan struct{}
+	writes chan struct{}
+}
+
+func newRateLimiter(perMinute, maxReads, maxWrites int) *rateLimiter {
+	return &rateLimiter{
+		tokens:    float64(perMinute),
+		capacity:  float64(perMinute),
+		refill:    time.Minute / time.Duration(perMinute),
+		updatedAt: time.Now(),
+		reads:     make(chan struct{}, maxReads),
+		writes:    make(chan struct{}, maxWrites),
+	}
+}
+
+// requestCost is what a request counts for against the quotas.
+type requestCost struct {
+	read   bool
+	tokens int
+}
+
+type requestCostKey struct{}
+
+// withRequestCost overrides the cost of the requests sent with ctx, which is
+// otherwise told by their method. A batch request, for instance, is a POST
+// counting as a read per action.
+func withRequestCost(ctx context.Context, read bool, tokens int) context.Context {
+	return context.WithValue(ctx, requestCostKey{}, requestCost{read, tokens})
+}
+
+// costOf returns the cost of a request with the given method sent with ctx.
+func costOf(ctx context.Context, method string) requestCost {
+	cost, found := ctx.Value(requestCostKey{}).(requestCost)
+	if !found {
+		cost = requestCost{read: method == http.MethodGet || method == http.MethodHead, tokens: 1}
+	}
+
+	return cost
+}
+
+// acquire waits until a request with the given method may be sent, and
+// returns the function releasing its concurrency slot once it is done.
+func (l *rateLimiter) acquire(ctx context.Context, method string) (func(), error) {
+	cost := costOf(ctx, method)
+	slots := l.writes
+	if cost.read {
+		slots = l.reads
+	}
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-slots }
+
+	for {
+		delay := l.reserve(cost.tokens)
+		if delay <= 0 {
+			return release, nil
+		}
+
+		err := sleepContext(ctx, delay)
+		if err != nil {
+			release()
+			return nil, err
+		}
+	}
+}
+
+// reserve takes n tokens, or otherwise returns how long to wait before
+// trying again. Requests costing more than the bucket holds wait for it to be
+// full.
+func (l *rateLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	l.tokens = min(l.capacity, l.tokens+float64(now.Sub(l.updatedAt))/float64(l.refill))
+	l.updatedAt = now
+	needed := min(float64(n), l.capacity)
+	if l.tokens >= needed {
+		l.tokens -= needed
+		return 0
+	}
+
+	return time.Duration((needed - l.tokens) * float64(l.refill))
+}
+
+// pause holds back every request for d, and drops the tokens left, since
+// Asana considers the quota as used up.
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if until := now.Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	l.tokens = 0
+	l.updatedAt = l.pausedUntil
+}
+
+// limitedClient sends the requests once the rate limiter allows them to,
+// and keeps their concurrency slot until their response body is closed.
+type limitedClient struct {
+	limiter *rateLimiter
+	client  angler.HTTPClient
+}
+
+func (c limitedClient) Do(req *http.Request) (*http.Response, error) {
+	release, err := c.limiter.acquire(req.Context(), req.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, found := parseRetryAfter(resp.Header.Get("Retry-After")); found {
+			c.limiter.pause(retryAfter)
+		}
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+// releasingBody releases the concurrency slot of its request once closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	b.once.Do(b.release)
+
+	return b.ReadCloser.Close()
+}
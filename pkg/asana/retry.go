@@ -0,0 +1,155 @@
+package asana
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy tells which failed requests are sent again, and how long to
+// wait before doing so. The delays grow exponentially from BaseDelay up to
+// MaxDelay, unless Asana responds with a Retry-After header, which is waited
+// for instead.
+type RetryPolicy struct {
+	// MaxAttempts bounds the attempts of a request, the first one included.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// FullJitter waits for a random delay between zero and the exponential
+	// one, so that the requests failed together are not retried together.
+	FullJitter bool
+	// RetryNetworkErrors retries the reads failed without a response, like the
+	// ones timed out or with the connection reset. The writes are never
+	// retried then, as Asana may have applied them anyway.
+	RetryNetworkErrors bool
+	// Statuses are the response statuses to retry, and their rules.
+	Statuses map[int]StatusRule
+}
+
+// StatusRule tells how the responses with a status are retried.
+type StatusRule struct {
+	// MaxAttempts bounds the attempts for the status below the policy ones,
+	// if set.
+	MaxAttempts int
+}
+
+// DefaultRetryPolicy retries the throttled requests, the network errors and
+// the server errors Asana documents as transient.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:        5,
+		BaseDelay:          500 * time.Millisecond,
+		MaxDelay:           30 * time.Second,
+		FullJitter:         true,
+		RetryNetworkErrors: true,
+		Statuses: map[int]StatusRule{
+			http.StatusTooManyRequests:     {},
+			http.StatusInternalServerError: {MaxAttempts: 3},
+			http.StatusBadGateway:          {},
+			http.StatusServiceUnavailable:  {},
+			http.StatusGatewayTimeout:      {},
+		},
+	}
+}
+
+// next returns the delay before retrying the attempt failed with err, or
+// false if it should not be retried. idempotent tells whether the request may
+// be sent again after failing without a response.
+func (p RetryPolicy) next(attempt int, err error, idempotent bool) (time.Duration, bool) {
+	maxAttempts := p.MaxAttempts
+
+	var (
+		apiErr *APIError
+		netErr net.Error
+	)
+	switch {
+	case err == nil:
+		return 0, false
+	case errors.As(err, &apiErr):
+		rule, found := p.Statuses[apiErr.StatusCode]
+		if !found {
+			return 0, false
+		}
+		if rule.MaxAttempts > 0 {
+			maxAttempts = min(maxAttempts, rule.MaxAttempts)
+		}
+		if attempt >= maxAttempts {
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter, true
+		}
+	case errors.As(err, &netErr):
+		if !p.RetryNetworkErrors || !idempotent || attempt >= maxAttempts {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	return p.backoff(attempt), true
+}
+
+// backoff returns the delay after the given attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 {
+		delay = min(delay, p.MaxDelay)
+	}
+	if p.FullJitter && delay > 0 {
+		delay = rand.N(delay + 1)
+	}
+
+	return delay
+}
+
+// retry runs action as long as the policy allows it, but stops as soon as ctx
+// is done, and returns the result of the last attempt along with the number
+// of attempts. action tells along with its result whether the request it sent
+// is idempotent.
+func retry[T any](ctx context.Context, policy RetryPolicy, action func() (T, bool, error)) (T, int, error) {
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, attempt - 1, err
+		}
+
+		result, idempotent, err := action()
+		delay, retryable := policy.next(attempt, err, idempotent)
+		if !retryable {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				var zero T
+				return zero, attempt, ctxErr
+			}
+			return result, attempt, err
+		}
+
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			var zero T
+			return zero, attempt, sleepErr
+		}
+	}
+}
+
+// parseRetryAfter parses the Retry-After header value, either as a number of
+// seconds, or as the date to retry at.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
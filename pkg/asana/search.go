@@ -0,0 +1,159 @@
+package asana
+
+import (
+	"maps"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SearchSortByDueDate     = "due_date"
+	SearchSortByCreatedAt   = "created_at"
+	SearchSortByCompletedAt = "completed_at"
+	SearchSortByModifiedAt  = "modified_at"
+	SearchSortByLikes       = "likes"
+
+	// searchWindow is the most tasks a single search request returns.
+	searchWindow = 100
+)
+
+// TaskSearch holds the filters of a workspace task search, set through its
+// builder methods:
+//
+//	search := NewTaskSearch(workspaceGID).
+//		Completed(false).
+//		DueBefore(time.Now()).
+//		CustomFieldValue(priorityGID, p1OptionGID)
+type TaskSearch struct {
+	workspaceGID string
+	query        url.Values
+	sortBy       string
+}
+
+func NewTaskSearch(workspaceGID string) *TaskSearch {
+	return &TaskSearch{
+		workspaceGID: workspaceGID,
+		query:        make(url.Values),
+	}
+}
+
+func (s *TaskSearch) Text(text string) *TaskSearch {
+	s.query.Set("text", text)
+	return s
+}
+
+func (s *TaskSearch) AssigneeAny(userGIDs ...string) *TaskSearch {
+	s.query.Set("assignee.any", strings.Join(userGIDs, ","))
+	return s
+}
+
+func (s *TaskSearch) AssigneeNot(userGIDs ...string) *TaskSearch {
+	s.query.Set("assignee.not", strings.Join(userGIDs, ","))
+	return s
+}
+
+func (s *TaskSearch) ProjectsAny(projectGIDs ...string) *TaskSearch {
+	s.query.Set("projects.any", strings.Join(projectGIDs, ","))
+	return s
+}
+
+func (s *TaskSearch) SectionsAny(sectionGIDs ...string) *TaskSearch {
+	s.query.Set("sections.any", strings.Join(sectionGIDs, ","))
+	return s
+}
+
+func (s *TaskSearch) TagsAny(tagGIDs ...string) *TaskSearch {
+	s.query.Set("tags.any", strings.Join(tagGIDs, ","))
+	return s
+}
+
+func (s *TaskSearch) Completed(completed bool) *TaskSearch {
+	s.query.Set("completed", strconv.FormatBool(completed))
+	return s
+}
+
+func (s *TaskSearch) IsSubtask(isSubtask bool) *TaskSearch {
+	s.query.Set("is_subtask", strconv.FormatBool(isSubtask))
+	return s
+}
+
+func (s *TaskSearch) DueBefore(date time.Time) *TaskSearch {
+	s.query.Set("due_on.before", date.Format(time.DateOnly))
+	return s
+}
+
+func (s *TaskSearch) DueAfter(date time.Time) *TaskSearch {
+	s.query.Set("due_on.after", date.Format(time.DateOnly))
+	return s
+}
+
+func (s *TaskSearch) ModifiedAfter(at time.Time) *TaskSearch {
+	s.query.Set("modified_at.after", at.UTC().Format(time.RFC3339))
+	return s
+}
+
+func (s *TaskSearch) ModifiedBefore(at time.Time) *TaskSearch {
+	s.query.Set("modified_at.before", at.UTC().Format(time.RFC3339))
+	return s
+}
+
+func (s *TaskSearch) CompletedAfter(at time.Time) *TaskSearch {
+	s.query.Set("completed_at.after", at.UTC().Format(time.RFC3339))
+	return s
+}
+
+func (s *TaskSearch) CompletedBefore(at time.Time) *TaskSearch {
+	s.query.Set("completed_at.before", at.UTC().Format(time.RFC3339))
+	return s
+}
+
+// CustomFieldValue matches the tasks whose custom field equals value; for
+// enum custom fields, the value is the GID of the enum option.
+func (s *TaskSearch) CustomFieldValue(customFieldGID, value string) *TaskSearch {
+	s.query.Set("custom_fields."+customFieldGID+".value", value)
+	return s
+}
+
+func (s *TaskSearch) CustomFieldIsSet(customFieldGID string, isSet bool) *TaskSearch {
+	s.query.Set("custom_fields."+customFieldGID+".is_set", strconv.FormatBool(isSet))
+	return s
+}
+
+func (s *TaskSearch) CustomFieldLessThan(customFieldGID string, value float64) *TaskSearch {
+	s.query.Set("custom_fields."+customFieldGID+".less_than", strconv.FormatFloat(value, 'f', -1, 64))
+	return s
+}
+
+func (s *TaskSearch) CustomFieldGreaterThan(customFieldGID string, value float64) *TaskSearch {
+	s.query.Set("custom_fields."+customFieldGID+".greater_than", strconv.FormatFloat(value, 'f', -1, 64))
+	return s
+}
+
+// SortBy orders the results by one of the SearchSortBy fields; Asana sorts
+// them by modification time by default. Only searches sorted by creation
+// time, from the most recent task, go beyond the first 100 results, so
+// Extractor.SearchTasks sorts them so unless told otherwise.
+func (s *TaskSearch) SortBy(field string, ascending bool) *TaskSearch {
+	s.sortBy = field
+	s.query.Set("sort_by", field)
+	s.query.Set("sort_ascending", strconv.FormatBool(ascending))
+	return s
+}
+
+// Query returns the search query parameters.
+func (s *TaskSearch) Query() url.Values {
+	return maps.Clone(s.query)
+}
+
+// pageable tells whether the search can go past its first results window.
+// The search API has no pagination; instead, the next window is requested
+// with a creation time bound taken from the last task of the previous one,
+// which works only while the tasks come sorted by their creation time, from
+// the most recent one. Searches without an explicit order are sorted so by
+// Extractor.SearchTasks.
+func (s *TaskSearch) pageable() bool {
+	return (s.sortBy == "" || s.sortBy == SearchSortByCreatedAt) && s.query.Get("sort_ascending") != "true" &&
+		s.query.Get("created_at.before") == ""
+}
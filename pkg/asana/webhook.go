@@ -0,0 +1,323 @@
+package asana
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CristianCurteanu/asana-extractor/pkg/storage"
+)
+
+const (
+	webhookSecretsFile = "webhooks/secrets.json"
+	webhookEventsFile  = "webhook_events.jsonl"
+
+	webhookPathPrefix  = "/webhooks/"
+	maxWebhookBodySize = 1 << 20
+)
+
+// WebhookReceiver serves the webhook targets, and turns the delivered events
+// into re-fetches of the changed resources, which are written to the storage.
+type WebhookReceiver interface {
+	http.Handler
+	// Register creates a webhook for each of the resources, targeting the
+	// receiver served at baseURL. The receiver must be served already, as Asana
+	// completes the handshake before the webhook is created.
+	Register(ctx context.Context, baseURL string, resourceGIDs []string) error
+	// Run processes the delivered events until ctx is done.
+	Run(ctx context.Context) error
+}
+
+type webhookReceiver struct {
+	apiclient APIClient
+	fs        storage.File
+	profiles  FieldProfiles
+	events    chan []Event
+
+	mx sync.Mutex
+	// secrets are the handshake secrets per resource GID; nil until loaded
+	secrets map[string]string
+	// pending are the resources whose webhook is being created, for which a
+	// handshake is expected
+	pending map[string]bool
+}
+
+// NewWebhookReceiver creates a WebhookReceiver, which serves the target of
+// each resource on the `/webhooks/<resource gid>` path. The handshake secrets
+// are kept in the storage, so that the webhooks keep working after a restart.
+// The changed resources are re-fetched with the fields of their profile, like
+// the extracted ones.
+func NewWebhookReceiver(apiclient APIClient, fs storage.File, profiles FieldProfiles) WebhookReceiver {
+	return &webhookReceiver{
+		apiclient: apiclient,
+		fs:        fs,
+		profiles:  profiles,
+		events:    make(chan []Event, 100),
+		pending:   make(map[string]bool),
+	}
+}
+
+func (r *webhookReceiver) Register(ctx context.Context, baseURL string, resourceGIDs []string) error {
+	for _, resource := range resourceGIDs {
+		r.mx.Lock()
+		err := r.loadSecrets()
+		_, registered := r.secrets[resource]
+		if err == nil && !registered {
+			r.pending[resource] = true
+		}
+		r.mx.Unlock()
+		if err != nil {
+			return err
+		}
+		if registered {
+			continue
+		}
+
+		_, err = r.apiclient.CreateWebhook(ctx, WebhookRequest{
+			Resource: resource,
+			Target:   strings.TrimSuffix(baseURL, "/") + webhookPathPrefix + url.PathEscape(resource),
+		})
+
+		r.mx.Lock()
+		delete(r.pending, resource)
+		r.mx.Unlock()
+		if err != nil {
+			return fmt.Errorf("unable to register webhook for %q resource: %w", resource, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resource, found := strings.CutPrefix(req.URL.Path, webhookPathPrefix)
+	if !found || resource == "" || strings.Contains(resource, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if secret := req.Header.Get("X-Hook-Secret"); secret != "" {
+		r.handshake(w, resource, secret)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxWebhookBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !r.verify(resource, body, req.Header.Get("X-Hook-Signature")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var delivery WebhookDelivery
+	err = json.Unmarshal(body, &delivery)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if len(delivery.Events) > 0 {
+		select {
+		case r.events <- delivery.Events:
+		case <-req.Context().Done():
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handshake stores the secret of a webhook being registered, and echoes it
+// back to Asana. Handshakes for resources not being registered are refused,
+// so the secrets can not be replaced by anyone else.
+func (r *webhookReceiver) handshake(w http.ResponseWriter, resource, secret string) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if !r.pending[resource] {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	err := r.loadSecrets()
+	if err == nil {
+		r.secrets[resource] = secret
+		err = r.saveSecrets()
+	}
+	if err != nil {
+		log.Printf("failed to store the webhook secret of %q resource, err=%q", resource, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Hook-Secret", secret)
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks that the signature is the HMAC-SHA256 of the body, keyed with
+// the handshake secret of the resource.
+func (r *webhookReceiver) verify(resource string, body []byte, signature string) bool {
+	r.mx.Lock()
+	err := r.loadSecrets()
+	secret, found := r.secrets[resource]
+	r.mx.Unlock()
+	if err != nil || !found {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func (r *webhookReceiver) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case events := <-r.events:
+			err := r.process(ctx, events)
+			if err != nil && ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				log.Printf("failed to process webhook events, err=%q", err)
+			}
+		}
+	}
+}
+
+// process stores the events, and the latest state of the tasks and projects
+// they changed. Events on other resources, like stories or attachments,
+// re-fetch their parent task or project instead.
+func (r *webhookReceiver) process(ctx context.Context, events []Event) error {
+	err := storage.AppendJSONLines(r.fs, webhookEventsFile, events)
+	if err != nil {
+		return err
+	}
+
+	var changed []Compact
+	for _, event := range events {
+		resource := event.Resource
+		if !refetchable(resource) {
+			if event.Parent == nil || !refetchable(*event.Parent) {
+				continue
+			}
+			resource = *event.Parent
+		} else if event.Action == "deleted" || event.Action == "removed" {
+			continue
+		}
+
+		if !containsResource(changed, resource) {
+			changed = append(changed, resource)
+		}
+	}
+
+	var errs []error
+	for _, resource := range changed {
+		err := r.refetch(ctx, resource)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to re-fetch %s %q: %w", resource.ResourceType, resource.GID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *webhookReceiver) refetch(ctx context.Context, resource Compact) error {
+	var (
+		res any
+		err error
+	)
+	switch resource.ResourceType {
+	case ResourceTypeTask:
+		query := make(url.Values)
+		query.Set("opt_fields", strings.Join(r.profiles.fields(ResourceTypeTask), ","))
+		res, err = r.apiclient.GetTask(ctx, resource.GID, query)
+	case ResourceTypeProject:
+		query := make(url.Values)
+		query.Set("opt_fields", strings.Join(r.profiles.fields(ResourceTypeProject), ","))
+		res, err = r.apiclient.GetProject(ctx, resource.GID, query)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return r.fs.Store(fmt.Sprintf("%d_%s_%s.json", time.Now().UTC().Unix(), resource.ResourceType, resource.GID), data)
+}
+
+func refetchable(resource Compact) bool {
+	return resource.ResourceType == ResourceTypeTask || resource.ResourceType == ResourceTypeProject
+}
+
+func containsResource(resources []Compact, resource Compact) bool {
+	for _, r := range resources {
+		if r.GID == resource.GID && r.ResourceType == resource.ResourceType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loadSecrets loads the stored secrets once; r.mx must be held.
+func (r *webhookReceiver) loadSecrets() error {
+	if r.secrets != nil {
+		return nil
+	}
+
+	data, err := r.fs.Read(webhookSecretsFile)
+	if err != nil {
+		return err
+	}
+
+	secrets := make(map[string]string)
+	if data != nil {
+		err = json.Unmarshal(data, &secrets)
+		if err != nil {
+			return fmt.Errorf("malformed webhook secrets: %w", err)
+		}
+	}
+	r.secrets = secrets
+
+	return nil
+}
+
+// saveSecrets stores the secrets; r.mx must be held.
+func (r *webhookReceiver) saveSecrets() error {
+	data, err := json.Marshal(r.secrets)
+	if err != nil {
+		return err
+	}
+
+	return r.fs.Store(webhookSecretsFile, data)
+}
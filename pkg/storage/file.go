@@ -1,6 +1,10 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -8,6 +12,9 @@ import (
 
 type File interface {
 	Store(file string, data []byte) error
+	Exists(file string) (bool, error)
+	Append(file string, data []byte) error
+	Read(file string) ([]byte, error)
 }
 
 type file struct {
@@ -20,12 +27,12 @@ func NewFile(dir string) File {
 
 // Store implements File.
 func (f *file) Store(file string, data []byte) error {
-	err := os.MkdirAll(f.dir, 0755)
+	fileOut := filepath.Join(f.dir, file)
+	err := os.MkdirAll(filepath.Dir(fileOut), 0755)
 	if err != nil {
 		return err
 	}
 
-	fileOut := filepath.Join(f.dir, file)
 	err = os.WriteFile(fileOut, data, 0644)
 	if err != nil {
 		log.Printf("failed to write users to the %q file, err=%q", fileOut, err)
@@ -33,3 +40,66 @@ func (f *file) Store(file string, data []byte) error {
 	}
 	return nil
 }
+
+// Exists implements File.
+func (f *file) Exists(file string) (bool, error) {
+	_, err := os.Stat(filepath.Join(f.dir, file))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Append implements File.
+func (f *file) Append(file string, data []byte) error {
+	fileOut := filepath.Join(f.dir, file)
+	err := os.MkdirAll(filepath.Dir(fileOut), 0755)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(fileOut, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+	if err != nil {
+		out.Close()
+		log.Printf("failed to append to the %q file, err=%q", fileOut, err)
+		return err
+	}
+	return out.Close()
+}
+
+// Read implements File; it returns no data if the file does not exist.
+func (f *file) Read(file string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(f.dir, file))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// AppendJSONLines appends the records to the file, one JSON record per line;
+// nothing is written if there are no records.
+func AppendJSONLines[T any](f File, file string, records []T) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	return f.Append(file, buf.Bytes())
+}
@@ -1,6 +1,7 @@
 package ticker
 
 import (
+	"context"
 	"log"
 	"sync"
 	"time"
@@ -11,59 +12,66 @@ var (
 	PeriodicExecution30sec = 30 * time.Second
 )
 
-type Handler func() error
+// Handler is a scheduled job; ctx is cancelled once the scheduler is stopped,
+// so long running jobs are expected to return early when it is done.
+type Handler func(ctx context.Context) error
 
 type Scheduler struct {
-	mx   sync.Mutex
-	stop chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewScheduler() *Scheduler {
+func NewScheduler(ctx context.Context) *Scheduler {
+	ctx, cancel := context.WithCancel(ctx)
+
 	return &Scheduler{
-		mx:   sync.Mutex{},
-		stop: make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
 func (s *Scheduler) Run(name string, duration time.Duration, handler Handler) {
 
 	log.Printf("added job for %q scheduler", name)
+	s.wg.Add(1)
 	go s.run(name, duration, handler)
 }
 
 func (s *Scheduler) run(name string, duration time.Duration, handler Handler) {
+	defer s.wg.Done()
 	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
 
 	running := true
 	for running {
 		select {
-		case <-s.stop:
-			ticker.Stop()
-			s.mx.Lock()
+		case <-s.ctx.Done():
 			running = false
-			s.mx.Unlock()
 		case <-ticker.C:
 			log.Printf("executing %q scheduled job", name)
-			err := handler()
+			err := handler(s.ctx)
 			if err != nil {
 				log.Printf("error while executing %q scheduled job: %q", name, err)
 			}
-			ticker.Stop()
-			ticker = time.NewTicker(duration)
+			ticker.Reset(duration)
 		}
 	}
 
 	log.Printf("ending %q scheduled job", name)
 }
 
+// Stop cancels the context passed to the running jobs, and waits for them to return.
 func (s *Scheduler) Stop() {
-	s.stop <- struct{}{}
-	close(s.stop)
+	s.cancel()
+	s.wg.Wait()
 	log.Println("scheduled job dispatcher closing")
 }
 
+// Wait blocks until the scheduler is stopped, or its parent context is done.
 func (s *Scheduler) Wait() {
-	<-s.stop
+	<-s.ctx.Done()
+	s.wg.Wait()
 }
 
 func GetExtractionPeriod(name string) (time.Duration, bool) {
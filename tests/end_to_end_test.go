@@ -1,13 +1,25 @@
 package tests
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/CristianCurteanu/asana-extractor/pkg/asana"
 	"github.com/CristianCurteanu/asana-extractor/pkg/storage"
@@ -32,11 +44,16 @@ func TestSuite(t *testing.T) {
 
 func (ts *EndToEndTestSuit) SetupTest() {
 	ts.asanaUrl = "https://app.asana.com/api/1.0"
-	ts.apiclient = asana.NewAPIClient(
-		ts.asanaUrl, "",
+	retryPolicy := asana.DefaultRetryPolicy()
+	retryPolicy.BaseDelay = 10 * time.Millisecond
+	retryPolicy.MaxDelay = 50 * time.Millisecond
+	apiclient, err := asana.NewAPIClient(
+		ts.asanaUrl, "", asana.WithRetryPolicy(retryPolicy),
 	)
+	ts.Require().NoError(err)
+	ts.apiclient = apiclient
 
-	ts.extractor = asana.NewExtractor(ts.apiclient)
+	ts.extractor = asana.NewExtractor(ts.apiclient, nil)
 	wd, err := os.Getwd()
 	ts.Require().NoError(err)
 	ts.wd = wd
@@ -45,13 +62,13 @@ func (ts *EndToEndTestSuit) SetupTest() {
 }
 
 func (ts *EndToEndTestSuit) Test_ExtractUsers_Success() {
-	users, err := ts.extractor.GetAllUsers()
+	users, err := ts.extractor.GetAllUsers(context.Background())
 	ts.Require().NoError(err)
 	ts.Require().NotEmpty(users)
 }
 
 func (ts *EndToEndTestSuit) Test_ExtractProjects_Success() {
-	projects, err := ts.extractor.GetAllProjects()
+	projects, err := ts.extractor.GetAllProjects(context.Background())
 	ts.Require().NoError(err)
 	ts.Require().NotEmpty(projects)
 }
@@ -68,11 +85,16 @@ func (ts *EndToEndTestSuit) Test_APIClient_ErrorIfUnauthorizedStatus() {
 				},
 			},
 		})))
-	_, _, err := ts.apiclient.ListUsers(url.Values{
+	_, _, err := ts.apiclient.ListUsers(context.Background(), url.Values{
 		"limit": []string{"100"},
 	})
 	ts.Require().Error(err)
-	ts.Require().ErrorContains(err, "bad HTTP response status response")
+	ts.Require().True(asana.IsUnauthorized(err))
+
+	var apiErr *asana.APIError
+	ts.Require().ErrorAs(err, &apiErr)
+	ts.Require().Equal("/users", apiErr.Path)
+	ts.Require().Equal("unauthorized", apiErr.Errors[0].Message)
 }
 
 func (ts *EndToEndTestSuit) Test_APIClient_ErrorIfBadRequestStatus() {
@@ -87,11 +109,1261 @@ func (ts *EndToEndTestSuit) Test_APIClient_ErrorIfBadRequestStatus() {
 				},
 			},
 		})))
-	_, _, err := ts.apiclient.ListUsers(url.Values{
+	_, _, err := ts.apiclient.ListUsers(context.Background(), url.Values{
 		"limit": []string{"100"},
 	})
 	ts.Require().Error(err)
-	ts.Require().ErrorContains(err, "bad HTTP response status response")
+
+	var apiErr *asana.APIError
+	ts.Require().ErrorAs(err, &apiErr)
+	ts.Require().Equal(http.StatusBadRequest, apiErr.StatusCode)
+	ts.Require().Equal("bad request", apiErr.Errors[0].Message)
+	ts.Require().False(asana.IsUnauthorized(err))
+}
+
+func (ts *EndToEndTestSuit) Test_APIClient_ErrorIfStatusWithoutHandler() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects/10").
+		Reply(http.StatusForbidden).
+		BodyString(`{"errors": [{"message": "Forbidden", "help": "For more information on API status codes, see https://developers.asana.com/docs/errors", "phrase": "6 sad squid snuggle softly"}]}`)
+
+	_, err := ts.apiclient.GetProject(context.Background(), "10", nil)
+	ts.Require().True(asana.IsForbidden(err))
+
+	var apiErr *asana.APIError
+	ts.Require().ErrorAs(err, &apiErr)
+	ts.Require().Equal("6 sad squid snuggle softly", apiErr.Errors[0].Phrase)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_APIClient_ErrorIfContextCancelled() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/users").
+		Reply(http.StatusOK).
+		BodyString(`{"data": []}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := ts.apiclient.ListUsers(ctx, url.Values{
+		"limit": []string{"100"},
+	})
+	ts.Require().ErrorIs(err, context.Canceled)
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractUsers_PaginatesEveryWorkspace() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		AddMatcher(withoutParam("offset")).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}], "next_page": {"offset": "ws-page-2"}}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		MatchParam("offset", "ws-page-2").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "2"}]}`)
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/workspace_memberships").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "101", "user": {"gid": "11"}, "is_guest": true, "is_active": true}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/2/workspace_memberships").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "201", "user": {"gid": "11"}, "is_admin": true, "is_active": false}]}`)
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/users").
+		MatchParam("workspace", "1").
+		AddMatcher(withoutParam("offset")).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "11"}], "next_page": {"offset": "users-page-2"}}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/users").
+		MatchParam("workspace", "1").
+		MatchParam("offset", "users-page-2").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "12"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/users").
+		MatchParam("workspace", "2").
+		AddMatcher(withoutParam("offset")).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "21"}, {"gid": "11"}]}`)
+
+	users, err := ts.extractor.GetAllUsers(context.Background())
+	ts.Require().NoError(err)
+
+	gids := make([]string, 0, len(users))
+	for _, user := range users {
+		gids = append(gids, user.GID)
+	}
+	ts.Require().Equal([]string{"11", "12", "21"}, gids)
+	ts.Require().Len(users[0].Workspaces, 2)
+	ts.Require().Len(users[0].WorkspaceMemberships, 2)
+	ts.Require().True(users[0].WorkspaceMemberships[0].IsGuest)
+	ts.Require().False(users[0].WorkspaceMemberships[1].IsActive)
+	ts.Require().Empty(users[1].WorkspaceMemberships)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractUsers_WithMembersNotListedAsUsers() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1", "name": "Acme"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/workspace_memberships").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [
+			{"gid": "101", "user": {"gid": "11", "name": "Active"}, "is_active": true},
+			{"gid": "102", "user": {"gid": "12", "name": "Deactivated"}, "is_active": false}
+		]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/users").
+		MatchParam("workspace", "1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "11", "name": "Active", "email": "active@example.com"}]}`)
+
+	users, err := ts.extractor.GetAllUsers(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(users, 2)
+	ts.Require().Equal("active@example.com", users[0].Email)
+	ts.Require().Len(users[0].WorkspaceMemberships, 1)
+
+	ts.Require().Equal("12", users[1].GID)
+	ts.Require().Equal("Deactivated", users[1].Name)
+	ts.Require().Equal([]asana.Compact{{GID: "1", ResourceType: asana.ResourceTypeWorkspace, Name: "Acme"}}, users[1].Workspaces)
+	ts.Require().Len(users[1].WorkspaceMemberships, 1)
+	ts.Require().False(users[1].WorkspaceMemberships[0].IsActive)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractTasks_SkipsTasksSharedBetweenProjects() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		MatchParam("workspace", "1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10"}, {"gid": "20"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tasks").
+		MatchParam("project", "10").
+		ParamPresent("opt_fields").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "100", "assignee": {"gid": "7", "name": "Matt"}}, {"gid": "200", "completed": true}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tasks").
+		MatchParam("project", "20").
+		ParamPresent("opt_fields").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "200", "completed": true}, {"gid": "300", "due_on": "2025-01-31"}]}`)
+
+	tasks, err := ts.extractor.GetAllTasks(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(tasks, 3)
+	ts.Require().Equal("Matt", tasks[0].Assignee.Name)
+	ts.Require().True(tasks[1].Completed)
+	ts.Require().Equal("2025-01-31", tasks[2].DueOn)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractTags_WithTasksAcrossPages() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/tags").
+		AddMatcher(withoutParam("offset")).
+		ParamPresent("opt_fields").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10", "name": "urgent", "color": "dark-red"}], "next_page": {"offset": "tags-page-2"}}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/tags").
+		MatchParam("offset", "tags-page-2").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "20", "name": "blocked"}]}`)
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tags/10/tasks").
+		AddMatcher(withoutParam("offset")).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "100", "name": "Ship it"}], "next_page": {"offset": "tasks-page-2"}}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tags/10/tasks").
+		MatchParam("offset", "tasks-page-2").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "200", "name": "Fix it"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tags/20/tasks").
+		Reply(http.StatusOK).
+		BodyString(`{"data": []}`)
+
+	tags, err := ts.extractor.GetAllTags(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(tags, 2)
+	ts.Require().Equal("urgent", tags[0].Name)
+	ts.Require().Equal("dark-red", *tags[0].Color)
+	ts.Require().Len(tags[0].Tasks, 2)
+	ts.Require().Equal("100", tags[0].Tasks[0].GID)
+	ts.Require().Equal("200", tags[0].Tasks[1].GID)
+	ts.Require().Equal("blocked", tags[1].Name)
+	ts.Require().Nil(tags[1].Color)
+	ts.Require().Empty(tags[1].Tasks)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractTasks_WalksSubtasks() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		MatchParam("workspace", "1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tasks").
+		MatchParam("project", "10").
+		MatchParam("opt_fields", "num_subtasks").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "100", "name": "Launch", "num_subtasks": 2}, {"gid": "200"}]}`)
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"relative_path":"/tasks/100/subtasks"`).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"status_code": 200, "body": {"data": [{"gid": "101", "num_subtasks": 1}, {"gid": "102"}]}}]}`)
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"relative_path":"/tasks/101/subtasks"`).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"status_code": 200, "body": {"data": [{"gid": "1011", "parent": {"gid": "101", "resource_type": "task", "name": "Docs"}}]}}]}`)
+
+	tasks, err := ts.extractor.GetAllTasks(context.Background())
+	ts.Require().NoError(err)
+
+	gids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		gids = append(gids, task.GID)
+	}
+	ts.Require().Equal([]string{"100", "200", "101", "102", "1011"}, gids)
+	ts.Require().Equal("Launch", tasks[2].Parent.Name)
+	ts.Require().Equal("Docs", tasks[4].Parent.Name)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractSections_PerProject() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		MatchParam("workspace", "1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10"}]}`)
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"relative_path":"/projects/10/sections"`).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"status_code": 200, "body": {"data": [{"gid": "11", "name": "In progress"}, {"gid": "12", "name": "Done"}]}}]}`)
+
+	sections, err := ts.extractor.GetAllSections(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(sections, 2)
+	ts.Require().Equal("Done", sections[1].Name)
+	ts.Require().True(gock.IsDone())
+
+	var task asana.Task
+	ts.Require().NoError(json.Unmarshal([]byte(`{
+		"gid": "100",
+		"memberships": [{"project": {"gid": "10"}, "section": {"gid": "12", "name": "Done"}}]
+	}`), &task))
+	ts.Require().Equal("12", task.SectionIn("10").GID)
+	ts.Require().Nil(task.SectionIn("20"))
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractTeams_OnlyForOrganizations() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1", "is_organization": false}, {"gid": "2", "is_organization": true}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/2/teams").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "20", "name": "Platform"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/teams/20/team_memberships").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "200", "user": {"gid": "1203891141552097", "name": "Matt"}, "is_admin": true}]}`)
+
+	teams, err := ts.extractor.GetAllTeams(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(teams, 1)
+	ts.Require().Len(teams[0].Memberships, 1)
+	ts.Require().Equal("1203891141552097", teams[0].Memberships[0].User.GID)
+	ts.Require().True(teams[0].Memberships[0].IsAdmin)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractPortfolios_WithNestedPortfolios() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/portfolios").
+		MatchParam("workspace", "1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10", "name": "Company goals"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/portfolios/10/items").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "100", "resource_type": "project"}, {"gid": "20", "resource_type": "portfolio"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/portfolios/20").
+		Reply(http.StatusOK).
+		BodyString(`{"data": {"gid": "20", "name": "Platform"}}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/portfolios/20/items").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "200", "resource_type": "project"}, {"gid": "10", "resource_type": "portfolio"}]}`)
+
+	portfolios, err := ts.extractor.GetAllPortfolios(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(portfolios, 2)
+	ts.Require().Equal("Platform", portfolios[1].Name)
+	ts.Require().Len(portfolios[1].Items, 2)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractGoals_BuildsGoalTree() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/goals").
+		MatchParam("workspace", "1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [
+			{"gid": "30", "name": "Ship v2"},
+			{"gid": "10", "name": "Grow revenue", "metric": {"target_number_value": 100, "current_number_value": 40}},
+			{"gid": "20", "name": "Launch in EU"}
+		]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/goal_relationships").
+		MatchParam("supported_goal", "30").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "301", "supporting_resource": {"gid": "300", "resource_type": "project"}}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/goal_relationships").
+		MatchParam("supported_goal", "10").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "101", "supporting_resource": {"gid": "20", "resource_type": "goal"}}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/goal_relationships").
+		MatchParam("supported_goal", "20").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "201", "supporting_resource": {"gid": "30", "resource_type": "goal"}}]}`)
+
+	trees, err := ts.extractor.GetAllGoals(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(trees, 1)
+	ts.Require().Len(trees[0].Goals, 1)
+
+	root := trees[0].Goals[0]
+	ts.Require().Equal("10", root.GID)
+	ts.Require().Equal(100.0, root.Metric.TargetNumberValue)
+	ts.Require().Len(root.Subgoals, 1)
+	ts.Require().Equal("20", root.Subgoals[0].GID)
+	ts.Require().Len(root.Subgoals[0].Subgoals, 1)
+	ts.Require().Equal("30", root.Subgoals[0].Subgoals[0].GID)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ArchiveAttachments_WithinLimits() {
+	defer gock.Off()
+
+	gock.New("https://asana-user-private.s3.amazonaws.com").
+		Get("/spec.pdf").
+		Reply(http.StatusOK).
+		SetHeader("Content-Type", "application/pdf").
+		BodyString("%PDF-1.7")
+	gock.New("https://asana-user-private.s3.amazonaws.com").
+		Get("/huge.pdf").
+		Reply(http.StatusOK).
+		SetHeader("Content-Type", "application/pdf").
+		BodyString("%PDF-1.7 and way more than the size cap")
+
+	downloadURL := func(name string) *string {
+		url := "https://asana-user-private.s3.amazonaws.com/" + name
+		return &url
+	}
+	attachments := []asana.Attachment{
+		{GID: "1", Name: "spec.pdf", DownloadURL: downloadURL("spec.pdf")},
+		{GID: "2", Name: "huge.pdf", DownloadURL: downloadURL("huge.pdf")},
+		{GID: "3", Name: "screenshot.png", DownloadURL: downloadURL("screenshot.png")},
+		{GID: "4", Name: "design", PermanentURL: "https://drive.google.com/file"},
+	}
+
+	outputDir := ts.T().TempDir()
+	archiver := asana.NewArchiver(ts.apiclient, storage.NewFile(outputDir), 16, []string{"application/pdf"})
+
+	archived, err := archiver.ArchiveAttachments(context.Background(), attachments)
+	ts.Require().NoError(err)
+	ts.Require().Equal("attachments/1/spec.pdf", archived[0].ArchivedAs)
+	ts.Require().Empty(archived[1].ArchivedAs)
+	ts.Require().Empty(archived[2].ArchivedAs)
+	ts.Require().Empty(archived[3].ArchivedAs)
+
+	content, err := readFile(filepath.Join(outputDir, "attachments", "1", "spec.pdf"))
+	ts.Require().NoError(err)
+	ts.Require().Equal("%PDF-1.7", string(content))
+}
+
+func (ts *EndToEndTestSuit) Test_ArchiveAttachments_RemembersRejectedContentTypes() {
+	defer gock.Off()
+
+	gock.New("https://asana-user-private.s3.amazonaws.com").
+		Get("/export").
+		Times(2).
+		Reply(http.StatusOK).
+		SetHeader("Content-Type", "application/zip").
+		BodyString("PK")
+
+	downloadURL := "https://asana-user-private.s3.amazonaws.com/export"
+	attachments := []asana.Attachment{{GID: "1", Name: "export", DownloadURL: &downloadURL}}
+	fs := storage.NewFile(ts.T().TempDir())
+
+	archiver := asana.NewArchiver(ts.apiclient, fs, 16, []string{"application/pdf"})
+	archived, err := archiver.ArchiveAttachments(context.Background(), attachments)
+	ts.Require().NoError(err)
+	ts.Require().Empty(archived[0].ArchivedAs)
+
+	// neither the next runs, nor the ones after a restart download it again
+	_, err = archiver.ArchiveAttachments(context.Background(), attachments)
+	ts.Require().NoError(err)
+	archiver = asana.NewArchiver(ts.apiclient, fs, 16, []string{"application/pdf"})
+	_, err = archiver.ArchiveAttachments(context.Background(), attachments)
+	ts.Require().NoError(err)
+	ts.Require().False(gock.IsDone())
+
+	// unless its content type gets allowed
+	archiver = asana.NewArchiver(ts.apiclient, fs, 16, []string{"application/zip"})
+	archived, err = archiver.ArchiveAttachments(context.Background(), attachments)
+	ts.Require().NoError(err)
+	ts.Require().Equal("attachments/1/export", archived[0].ArchivedAs)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_Task_DecodesTypedCustomFieldValues() {
+	var task asana.Task
+	ts.Require().NoError(json.Unmarshal([]byte(`{
+		"gid": "100",
+		"custom_fields": [
+			{"gid": "1", "name": "Priority", "resource_subtype": "enum", "display_value": "P1", "enum_value": {"gid": "11", "name": "P1", "color": "red", "enabled": true}},
+			{"gid": "2", "name": "Estimate", "resource_subtype": "number", "display_value": "2.5", "precision": 1, "number_value": 2.5},
+			{"gid": "3", "name": "Launch", "resource_subtype": "date", "date_value": {"date": "2025-03-01", "date_time": null}},
+			{"gid": "4", "name": "Reviewers", "resource_subtype": "people", "people_value": [{"gid": "7", "name": "Matt"}]}
+		]
+	}`), &task))
+
+	ts.Require().Equal("P1", task.CustomField("Priority").EnumValue.Name)
+	ts.Require().Equal(2.5, *task.CustomField("Estimate").NumberValue)
+	ts.Require().Equal("2025-03-01", task.CustomField("Launch").DateValue.Date)
+	ts.Require().Equal("Matt", task.CustomField("Reviewers").PeopleValue[0].Name)
+	ts.Require().Nil(task.CustomField("Missing"))
+}
+
+func (ts *EndToEndTestSuit) Test_TimeTrackingReport_SumsPerProjectAndUser() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tasks").
+		MatchParam("project", "10").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [
+			{"gid": "100", "memberships": [{"project": {"gid": "10", "name": "Website"}}]},
+			{"gid": "200", "memberships": [{"project": {"gid": "10", "name": "Website"}}]}
+		]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tasks/100/time_tracking_entries").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [
+			{"gid": "1001", "duration_minutes": 30, "created_by": {"gid": "7", "name": "Matt"}},
+			{"gid": "1002", "duration_minutes": 45, "created_by": {"gid": "8", "name": "Steffen"}, "attributable_to": {"gid": "20", "name": "Billing"}}
+		]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tasks/200/time_tracking_entries").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "2001", "duration_minutes": 60, "created_by": {"gid": "7", "name": "Matt"}}]}`)
+
+	report, err := ts.extractor.GetTimeTrackingReport(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(report.Entries, 3)
+	ts.Require().Equal("100", report.Entries[0].Task.GID)
+
+	ts.Require().Len(report.ByProject, 2)
+	ts.Require().Equal("10", report.ByProject[0].GID)
+	ts.Require().Equal(90, report.ByProject[0].DurationMinutes)
+	ts.Require().Equal(45, report.ByProject[1].DurationMinutes)
+
+	ts.Require().Len(report.ByUser, 2)
+	ts.Require().Equal(90, report.ByUser[0].DurationMinutes)
+	ts.Require().Equal(45, report.ByUser[1].DurationMinutes)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractProjects_WithMembershipsAndPrivacy() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		MatchParam("workspace", "1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10", "privacy_setting": "private_to_team"}]}`)
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"data":{"parent":"10"}`).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"status_code": 200, "body": {"data": [
+			{"gid": "101", "member": {"gid": "7", "resource_type": "user"}, "access_level": "admin"},
+			{"gid": "102", "member": {"gid": "20", "resource_type": "team"}, "access_level": "commenter"}
+		]}}]}`)
+
+	projects, err := ts.extractor.GetAllProjects(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(projects, 1)
+	ts.Require().Equal("private_to_team", projects[0].PrivacySetting)
+	ts.Require().Len(projects[0].Memberships, 2)
+	ts.Require().Equal("team", projects[0].Memberships[1].Member.ResourceType)
+	ts.Require().Equal("commenter", projects[0].Memberships[1].AccessLevel)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_AuditLogSync_ResumesWithoutDuplicates() {
+	defer gock.Off()
+
+	fs := storage.NewFile(ts.T().TempDir())
+	ts.Require().NoError(fs.Store("audit_log/1.state.json", []byte(`{"offset": "page-2", "events": 1}`)))
+	ts.Require().NoError(fs.Append("audit_log/1.jsonl", []byte("{\"gid\": \"e1\"}\n{\"gid\": \"e2\"}\n")))
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/audit_log_events").
+		MatchParam("offset", "page-2").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "e2"}, {"gid": "e3"}], "next_page": {"offset": "page-3"}}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/audit_log_events").
+		MatchParam("offset", "page-3").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [], "next_page": {"offset": "page-4"}}`)
+
+	auditLog := asana.NewAuditLogSyncer(ts.apiclient, fs, "1", asana.AuditLogQuery{})
+	ts.Require().NoError(auditLog.Sync(context.Background()))
+	ts.Require().True(gock.IsDone())
+
+	events, err := fs.Read("audit_log/1.jsonl")
+	ts.Require().NoError(err)
+
+	var gids []string
+	for line := range strings.Lines(string(events)) {
+		var event asana.AuditLogEvent
+		ts.Require().NoError(json.Unmarshal([]byte(line), &event))
+		gids = append(gids, event.GID)
+	}
+	ts.Require().Equal([]string{"e1", "e2", "e3"}, gids)
+
+	state, err := fs.Read("audit_log/1.state.json")
+	ts.Require().NoError(err)
+	ts.Require().JSONEq(`{"offset": "page-4", "events": 3}`, string(state))
+}
+
+func (ts *EndToEndTestSuit) Test_AuditLogSync_WithoutCursorDoesNotDuplicate() {
+	defer gock.Off()
+
+	fs := storage.NewFile(ts.T().TempDir())
+	ts.Require().NoError(fs.Store("audit_log/1.state.json", []byte(`{"offset": "page-1", "events": 0}`)))
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/audit_log_events").
+		MatchParam("offset", "page-1").
+		Times(2).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "e1"}], "next_page": null}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/audit_log_events").
+		MatchParam("offset", "page-1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "e1"}, {"gid": "e2"}], "next_page": {"offset": "page-2"}}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/audit_log_events").
+		MatchParam("offset", "page-2").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [], "next_page": {"offset": "page-3"}}`)
+
+	auditLog := asana.NewAuditLogSyncer(ts.apiclient, fs, "1", asana.AuditLogQuery{})
+	ts.Require().NoError(auditLog.Sync(context.Background()))
+	ts.Require().NoError(auditLog.Sync(context.Background()))
+
+	// the page is fetched again after a restart as well
+	auditLog = asana.NewAuditLogSyncer(ts.apiclient, fs, "1", asana.AuditLogQuery{})
+	ts.Require().NoError(auditLog.Sync(context.Background()))
+	ts.Require().True(gock.IsDone())
+
+	events, err := fs.Read("audit_log/1.jsonl")
+	ts.Require().NoError(err)
+
+	var gids []string
+	for line := range strings.Lines(string(events)) {
+		var event asana.AuditLogEvent
+		ts.Require().NoError(json.Unmarshal([]byte(line), &event))
+		gids = append(gids, event.GID)
+	}
+	ts.Require().Equal([]string{"e1", "e2"}, gids)
+
+	state, err := fs.Read("audit_log/1.state.json")
+	ts.Require().NoError(err)
+	ts.Require().JSONEq(`{"offset": "page-3", "events": 2}`, string(state))
+}
+
+func (ts *EndToEndTestSuit) Test_EventsSync_FallsBackToFullCrawl() {
+	defer gock.Off()
+
+	// the projects are listed compact by every sync, and once more by every
+	// crawl after the first sync
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Times(6).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		MatchParam("workspace", "1").
+		AddMatcher(withoutParam("opt_fields")).
+		Times(6).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10"}]}`)
+
+	// the first sync crawls everything, and issues the sync token
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/events").
+		MatchParam("resource", "10").
+		AddMatcher(withoutParam("sync")).
+		Reply(http.StatusPreconditionFailed).
+		BodyString(`{"errors": [{"message": "Sync token invalid or too old"}], "sync": "token-1"}`)
+
+	// the second sync stores the changes
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/events").
+		MatchParam("sync", "token-1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"action": "changed", "resource": {"gid": "100", "resource_type": "task"}, "change": {"field": "completed", "action": "changed", "new_value": true}}], "sync": "token-2", "has_more": false}`)
+
+	// the third and the fourth syncs find the token expired, so everything is
+	// crawled again; the first of these crawls fails, and is run once more
+	for _, token := range []string{"token-3", "token-4"} {
+		gock.New("https://app.asana.com").
+			Get("/api/1.0/events").
+			MatchParam("sync", "token-2").
+			Reply(http.StatusPreconditionFailed).
+			BodyString(`{"errors": [{"message": "Sync token invalid or too old"}], "sync": "expired"}`)
+		gock.New("https://app.asana.com").
+			Get("/api/1.0/events").
+			MatchParam("resource", "10").
+			AddMatcher(withoutParam("sync")).
+			Reply(http.StatusPreconditionFailed).
+			BodyString(`{"errors": [{"message": "Sync token invalid or too old"}], "sync": "` + token + `"}`)
+	}
+
+	fs := storage.NewFile(ts.T().TempDir())
+	crawls := 0
+	events := asana.NewEventsConsumer(ts.apiclient, ts.extractor, fs, func(context.Context) error {
+		crawls++
+		if crawls == 2 {
+			return errors.New("crawl failed")
+		}
+		return nil
+	})
+
+	ts.Require().NoError(events.Sync(context.Background()))
+	ts.Require().Equal(1, crawls)
+	ts.Require().NoError(events.Sync(context.Background()))
+	ts.Require().Equal(1, crawls)
+	ts.Require().Error(events.Sync(context.Background()))
+	ts.Require().Equal(2, crawls)
+	ts.Require().NoError(events.Sync(context.Background()))
+	ts.Require().Equal(3, crawls)
+	ts.Require().True(gock.IsDone())
+
+	stored, err := fs.Read("events.jsonl")
+	ts.Require().NoError(err)
+	var event asana.Event
+	ts.Require().NoError(json.Unmarshal(stored, &event))
+	ts.Require().Equal("completed", event.Change.Field)
+
+	tokens, err := fs.Read("events_sync_tokens.json")
+	ts.Require().NoError(err)
+	ts.Require().JSONEq(`{"10": "token-4"}`, string(tokens))
+}
+
+func (ts *EndToEndTestSuit) Test_EventsSync_FollowsNewProjects() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		MatchParam("workspace", "1").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10"}, {"gid": "20"}]}`)
+
+	// the project created since the last sync gets a token, while the removed
+	// one is not followed anymore
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/events").
+		MatchParam("resource", "20").
+		AddMatcher(withoutParam("sync")).
+		Reply(http.StatusPreconditionFailed).
+		BodyString(`{"errors": [{"message": "Sync token invalid or too old"}], "sync": "token-20"}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/events").
+		MatchParam("resource", "10").
+		MatchParam("sync", "token-10").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [], "sync": "token-11", "has_more": false}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/events").
+		MatchParam("resource", "20").
+		MatchParam("sync", "token-20").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [], "sync": "token-21", "has_more": false}`)
+
+	fs := storage.NewFile(ts.T().TempDir())
+	ts.Require().NoError(fs.Store("events_sync_tokens.json", []byte(`{"10": "token-10", "30": "token-30"}`)))
+	events := asana.NewEventsConsumer(ts.apiclient, ts.extractor, fs, func(context.Context) error {
+		ts.Fail("the projects are crawled")
+		return nil
+	})
+
+	ts.Require().NoError(events.Sync(context.Background()))
+	ts.Require().True(gock.IsDone())
+
+	tokens, err := fs.Read("events_sync_tokens.json")
+	ts.Require().NoError(err)
+	ts.Require().JSONEq(`{"10": "token-11", "20": "token-21"}`, string(tokens))
+}
+
+func (ts *EndToEndTestSuit) Test_WebhookReceiver_RefetchesSignedEvents() {
+	defer gock.Off()
+
+	outputDir := ts.T().TempDir()
+	fs := storage.NewFile(outputDir)
+	profiles, err := asana.ParseFieldProfiles("task=lean")
+	ts.Require().NoError(err)
+	receiver := asana.NewWebhookReceiver(ts.apiclient, fs, profiles)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go receiver.Run(ctx)
+
+	// the fake server is reached directly, and not through gock
+	client := &http.Client{Transport: &http.Transport{}}
+	post := func(path string, headers map[string]string, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+		ts.Require().NoError(err)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := client.Do(req)
+		ts.Require().NoError(err)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	// Asana sends the handshake while the webhook is being created
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/webhooks").
+		AddMatcher(func(*http.Request, *gock.Request) (bool, error) {
+			resp := post("/webhooks/10", map[string]string{"X-Hook-Secret": "s3cr3t"}, "")
+			return resp.StatusCode == http.StatusOK && resp.Header.Get("X-Hook-Secret") == "s3cr3t", nil
+		}).
+		Reply(http.StatusCreated).
+		BodyString(`{"data": {"gid": "1", "resource": {"gid": "10"}, "active": true}}`)
+	ts.Require().NoError(receiver.Register(ctx, server.URL, []string{"10"}))
+
+	// handshakes are refused once the webhook is registered
+	resp := post("/webhooks/10", map[string]string{"X-Hook-Secret": "stolen"}, "")
+	ts.Require().Equal(http.StatusForbidden, resp.StatusCode)
+
+	// the task is re-fetched with the fields of its profile
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tasks/100").
+		MatchParam("opt_fields", "^name,assignee.name,completed,due_on,memberships.project.name,memberships.section.name$").
+		Reply(http.StatusOK).
+		BodyString(`{"data": {"gid": "100", "name": "Write docs", "completed": true}}`)
+
+	body := `{"events": [
+		{"action": "changed", "resource": {"gid": "100", "resource_type": "task"}, "parent": null},
+		{"action": "added", "resource": {"gid": "1001", "resource_type": "story"}, "parent": {"gid": "100", "resource_type": "task"}}
+	]}`
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte(body))
+
+	resp = post("/webhooks/10", map[string]string{"X-Hook-Signature": hex.EncodeToString([]byte("forged"))}, body)
+	ts.Require().Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	resp = post("/webhooks/10", map[string]string{"X-Hook-Signature": hex.EncodeToString(mac.Sum(nil))}, body)
+	ts.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	// both events change the same task, which is re-fetched once
+	ts.Require().Eventually(func() bool {
+		files, _ := filepath.Glob(filepath.Join(outputDir, "*_task_100.json"))
+		return len(files) == 1
+	}, time.Second, 10*time.Millisecond)
+	ts.Require().True(gock.IsDone())
+
+	events, err := fs.Read("webhook_events.jsonl")
+	ts.Require().NoError(err)
+	ts.Require().Len(strings.Split(strings.TrimSpace(string(events)), "\n"), 2)
+}
+
+func (ts *EndToEndTestSuit) Test_WebhookReceiver_RefusesHandshakesAfterRestart() {
+	defer gock.Off()
+
+	fs := storage.NewFile(ts.T().TempDir())
+	ts.Require().NoError(fs.Store("webhooks/secrets.json", []byte(`{"10": "s3cr3t"}`)))
+
+	// the restarted receiver registers the stored resource again, without
+	// creating its webhook
+	receiver := asana.NewWebhookReceiver(ts.apiclient, fs, nil)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	ts.Require().NoError(receiver.Register(context.Background(), server.URL, []string{"10"}))
+
+	client := &http.Client{Transport: &http.Transport{}}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/webhooks/10", nil)
+	ts.Require().NoError(err)
+	req.Header.Set("X-Hook-Secret", "attacker")
+	resp, err := client.Do(req)
+	ts.Require().NoError(err)
+	resp.Body.Close()
+	ts.Require().Equal(http.StatusForbidden, resp.StatusCode)
+
+	secrets, err := fs.Read("webhooks/secrets.json")
+	ts.Require().NoError(err)
+	ts.Require().JSONEq(`{"10": "s3cr3t"}`, string(secrets))
+}
+
+func (ts *EndToEndTestSuit) Test_SearchTasks_PagesThroughResultWindows() {
+	defer gock.Off()
+
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := make([]asana.Task, 0, 100)
+	for i := range 100 {
+		createdAt := createdAt.Add(-time.Duration(i) * time.Minute)
+		window = append(window, asana.Task{GID: strconv.Itoa(i + 1), CreatedAt: &createdAt})
+	}
+	last := window[99]
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/tasks/search").
+		MatchParam("completed", "false").
+		MatchParam("sort_by", "created_at").
+		MatchParam("sort_ascending", "false").
+		MatchParam("assignee.any", "7,8").
+		MatchParam("custom_fields.50.value", "51").
+		AddMatcher(withoutParam("created_at.before")).
+		Reply(http.StatusOK).
+		BodyString(string(ts.encodeJSON(asana.MultipleResponse[asana.Task]{Data: window})))
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/tasks/search").
+		MatchParam("completed", "false").
+		MatchParam("sort_by", "created_at").
+		MatchParam("created_at.before", regexp.QuoteMeta(last.CreatedAt.Add(time.Millisecond).Format(time.RFC3339Nano))).
+		Reply(http.StatusOK).
+		BodyString(string(ts.encodeJSON(asana.MultipleResponse[asana.Task]{Data: []asana.Task{last, {GID: "101"}}})))
+
+	search := asana.NewTaskSearch("1").
+		Completed(false).
+		AssigneeAny("7", "8").
+		CustomFieldValue("50", "51")
+
+	tasks, err := ts.extractor.SearchTasks(context.Background(), search)
+	ts.Require().NoError(err)
+	ts.Require().Len(tasks, 101)
+	ts.Require().Equal("101", tasks[100].GID)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_SearchTasks_PagesWithLeanProfile() {
+	defer gock.Off()
+
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := make([]asana.Task, 0, 100)
+	for i := range 100 {
+		createdAt := createdAt.Add(-time.Duration(i) * time.Minute)
+		window = append(window, asana.Task{GID: strconv.Itoa(i + 1), CreatedAt: &createdAt})
+	}
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/tasks/search").
+		MatchParam("opt_fields", "^name,.*,created_at$").
+		AddMatcher(withoutParam("created_at.before")).
+		Reply(http.StatusOK).
+		BodyString(string(ts.encodeJSON(asana.MultipleResponse[asana.Task]{Data: window})))
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces/1/tasks/search").
+		MatchParam("opt_fields", "created_at").
+		ParamPresent("created_at.before").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "101", "created_at": "2024-12-01T00:00:00Z"}]}`)
+
+	profiles, err := asana.ParseFieldProfiles("task=lean")
+	ts.Require().NoError(err)
+	extractor := asana.NewExtractor(ts.apiclient, profiles)
+
+	tasks, err := extractor.SearchTasks(context.Background(), asana.NewTaskSearch("1"))
+	ts.Require().NoError(err)
+	ts.Require().Len(tasks, 101)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractStories_BatchesRequestsPerTask() {
+	defer gock.Off()
+
+	tasks := make([]asana.Task, 0, 11)
+	for i := range 11 {
+		tasks = append(tasks, asana.Task{GID: strconv.Itoa(i + 1)})
+	}
+	stories := func(gid string) asana.BatchResult {
+		return asana.BatchResult{StatusCode: http.StatusOK, Body: ts.encodeJSON(asana.MultipleResponse[asana.Story]{Data: []asana.Story{{GID: gid}}})}
+	}
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/tasks").
+		MatchParam("project", "10").
+		Reply(http.StatusOK).
+		BodyString(string(ts.encodeJSON(asana.MultipleResponse[asana.Task]{Data: tasks})))
+
+	// the first batch holds the first page of the first 10 tasks only
+	firstBatch := make([]asana.BatchResult, 0, 10)
+	for i := range 10 {
+		firstBatch = append(firstBatch, stories(fmt.Sprintf("s%d", i+1)))
+	}
+	firstBatch[0].Body = ts.encodeJSON(asana.MultipleResponse[asana.Story]{
+		Data:     []asana.Story{{GID: "s1"}},
+		NextPage: &asana.NextPage{Offset: "page-2"},
+	})
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"relative_path":"/tasks/10/stories"`).
+		Reply(http.StatusOK).
+		BodyString(string(ts.encodeJSON(asana.SingleResponse[[]asana.BatchResult]{Data: firstBatch})))
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"relative_path":"/tasks/11/stories"`).
+		Reply(http.StatusOK).
+		BodyString(string(ts.encodeJSON(asana.SingleResponse[[]asana.BatchResult]{Data: []asana.BatchResult{stories("s11")}})))
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"offset":"page-2"`).
+		Reply(http.StatusOK).
+		BodyString(string(ts.encodeJSON(asana.SingleResponse[[]asana.BatchResult]{Data: []asana.BatchResult{stories("s1-2")}})))
+
+	res, err := ts.extractor.GetAllStories(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(res, 12)
+	ts.Require().Equal("s1", res[0].GID)
+	ts.Require().Equal("s1-2", res[1].GID)
+	ts.Require().Equal("s11", res[11].GID)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractProjects_WithFieldProfiles() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		MatchParam("opt_fields", "name,is_organization,email_domains").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1", "name": "Acme", "is_organization": true, "email_domains": ["acme.com"]}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		MatchParam("workspace", "1").
+		MatchParam("opt_fields", "^name,archived,privacy_setting,public$").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10", "name": "Roadmap", "archived": true, "privacy_setting": "public_to_workspace"}]}`)
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"status_code": 200, "body": {"data": []}}]}`)
+
+	extractor := asana.NewExtractor(ts.apiclient, asana.FieldProfiles{asana.ResourceTypeProject: asana.FieldProfileLean})
+	projects, err := extractor.GetAllProjects(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(projects, 1)
+	ts.Require().Equal("Roadmap", projects[0].Name)
+	ts.Require().True(projects[0].Archived)
+	ts.Require().Nil(projects[0].Owner)
+	ts.Require().True(gock.IsDone())
+
+	_, err = asana.ParseFieldProfiles("project=huge")
+	ts.Require().Error(err)
+}
+
+func (ts *EndToEndTestSuit) Test_APIClient_PausesEveryRequestWhenThrottled() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/users").
+		Reply(http.StatusTooManyRequests).
+		SetHeader("Retry-After", "1")
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/users").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "7"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+
+	usersErr := make(chan error, 1)
+	go func() {
+		_, _, err := ts.apiclient.ListUsers(context.Background(), url.Values{})
+		usersErr <- err
+	}()
+
+	// the workspaces are requested once the users request is throttled, yet
+	// they have to wait for the throttling to end as well
+	time.Sleep(200 * time.Millisecond)
+	start := time.Now()
+	workspaces, _, err := ts.apiclient.ListWorkspaces(context.Background(), url.Values{})
+	ts.Require().NoError(err)
+	ts.Require().Len(workspaces, 1)
+	ts.Require().GreaterOrEqual(time.Since(start), 700*time.Millisecond)
+
+	ts.Require().NoError(<-usersErr)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_APIClient_RetriesServerErrors() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusServiceUnavailable).
+		SetHeader("Retry-After", time.Now().Add(time.Second).UTC().Format(http.TimeFormat))
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusBadGateway)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+
+	workspaces, _, err := ts.apiclient.ListWorkspaces(context.Background(), url.Values{})
+	ts.Require().NoError(err)
+	ts.Require().Len(workspaces, 1)
+	ts.Require().True(gock.IsDone())
+
+	// internal errors are retried fewer times than the other ones
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/users").
+		Times(3).
+		Reply(http.StatusInternalServerError).
+		BodyString(`{"errors": [{"message": "Server Error"}]}`)
+
+	_, _, err = ts.apiclient.ListUsers(context.Background(), url.Values{})
+	var apiErr *asana.APIError
+	ts.Require().ErrorAs(err, &apiErr)
+	ts.Require().Equal(http.StatusInternalServerError, apiErr.StatusCode)
+	ts.Require().Equal(2, apiErr.Retries)
+	ts.Require().True(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_APIClient_RetriesNetworkErrorsOnlyForReads() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		ReplyError(errors.New("connection reset by peer"))
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+
+	workspaces, _, err := ts.apiclient.ListWorkspaces(context.Background(), url.Values{})
+	ts.Require().NoError(err)
+	ts.Require().Len(workspaces, 1)
+	ts.Require().True(gock.IsDone())
+
+	// the webhook may have been created anyway, so it is not created again
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/webhooks").
+		ReplyError(errors.New("connection reset by peer"))
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/webhooks").
+		Reply(http.StatusCreated).
+		BodyString(`{"data": {"gid": "1"}}`)
+
+	_, err = ts.apiclient.CreateWebhook(context.Background(), asana.WebhookRequest{Resource: "1", Target: "https://example.com/webhooks/1"})
+	ts.Require().Error(err)
+	ts.Require().False(gock.IsDone())
+}
+
+func (ts *EndToEndTestSuit) Test_APIClient_WithLocalServer() {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.Equal("/api/1.0/workspaces", r.URL.Path)
+		ts.Equal("Bearer token", r.Header.Get("Authorization"))
+		ts.Equal("extractor-tests", r.Header.Get("User-Agent"))
+		ts.Equal("on", r.Header.Get("Asana-Enable"))
+		w.Write([]byte(`{"data": [{"gid": "1", "name": "Acme"}]}`))
+	}))
+	defer server.Close()
+
+	apiclient, err := asana.NewAPIClient(server.URL+"/api/1.0", "token",
+		asana.WithHTTPClient(server.Client()),
+		asana.WithTimeout(time.Second),
+		asana.WithUserAgent("extractor-tests"),
+		asana.WithHeader("Asana-Enable", "on"),
+		asana.WithHeader("Authorization", "Bearer overridden"),
+	)
+	ts.Require().NoError(err)
+	workspaces, _, err := apiclient.ListWorkspaces(context.Background(), url.Values{})
+	ts.Require().NoError(err)
+	ts.Require().Len(workspaces, 1)
+	ts.Require().Equal("Acme", workspaces[0].Name)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (ts *EndToEndTestSuit) Test_NewAPIClient_RefusesProxyOnCustomTransport() {
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	proxyURL, err := url.Parse("http://proxy.internal:3128")
+	ts.Require().NoError(err)
+
+	_, err = asana.NewAPIClient(ts.asanaUrl, "",
+		asana.WithTransport(transport),
+		asana.WithProxy(proxyURL),
+	)
+	ts.Require().Error(err)
+
+	_, err = asana.NewAPIClient(ts.asanaUrl, "",
+		asana.WithTransport(transport),
+		asana.WithRootCAs(x509.NewCertPool()),
+	)
+	ts.Require().Error(err)
+
+	_, err = asana.NewAPIClient(ts.asanaUrl, "",
+		asana.WithTransport(&http.Transport{}),
+		asana.WithProxy(proxyURL),
+	)
+	ts.Require().NoError(err)
+}
+
+func (ts *EndToEndTestSuit) Test_NewAPIClient_RefusesInvalidRateLimit() {
+	for _, limits := range [][3]int{{0, 50, 15}, {1500, 0, 15}, {1500, 50, 0}, {-1, 50, 15}} {
+		_, err := asana.NewAPIClient(ts.asanaUrl, "", asana.WithRateLimit(limits[0], limits[1], limits[2]))
+		ts.Require().Error(err, "limits %v", limits)
+	}
+
+	_, err := asana.NewAPIClient(ts.asanaUrl, "", asana.WithRateLimit(150, 5, 5))
+	ts.Require().NoError(err)
+}
+
+func (ts *EndToEndTestSuit) Test_ExtractSections_RetriesThrottledBatchActions() {
+	defer gock.Off()
+
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/workspaces").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "1"}]}`)
+	gock.New("https://app.asana.com").
+		Get("/api/1.0/projects").
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"gid": "10"}, {"gid": "20"}]}`)
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"relative_path":"/projects/10/sections"`).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [
+			{"status_code": 200, "body": {"data": [{"gid": "11", "name": "To do"}]}},
+			{"status_code": 429, "headers": {"retry-after": "1"}, "body": {"errors": [{"message": "Rate limited"}]}}
+		]}`)
+	gock.New("https://app.asana.com").
+		Post("/api/1.0/batch").
+		BodyString(`"relative_path":"/projects/20/sections"`).
+		Reply(http.StatusOK).
+		BodyString(`{"data": [{"status_code": 200, "body": {"data": [{"gid": "21", "name": "Done"}]}}]}`)
+
+	start := time.Now()
+	sections, err := ts.extractor.GetAllSections(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(sections, 2)
+	ts.Require().Equal("Done", sections[1].Name)
+	ts.Require().GreaterOrEqual(time.Since(start), time.Second)
+	ts.Require().True(gock.IsDone())
 }
 
 // TODO: Finish this test
@@ -105,8 +1377,8 @@ func (ts *EndToEndTestSuit) Test_EndToEndExtraction_Success() {
 	// 	BodyString(string(usersData))
 
 	// Get All the data
-	// users, err := ts.extractor.GetAllUsers()
-	// users, _, err := ts.apiclient.ListUsers(url.Values{
+	// users, err := ts.extractor.GetAllUsers(context.Background())
+	// users, _, err := ts.apiclient.ListUsers(context.Background(), url.Values{
 	// 	"limit": []string{"100"},
 	// })
 	// ts.Require().NoError(err)
@@ -124,6 +1396,12 @@ func (s *EndToEndTestSuit) encodeJSON(data any) []byte {
 	return encoded
 }
 
+func withoutParam(key string) gock.MatchFunc {
+	return func(req *http.Request, _ *gock.Request) (bool, error) {
+		return !req.URL.Query().Has(key), nil
+	}
+}
+
 func readFile(path string) ([]byte, error) {
 	jsonFile, err := os.Open(path)
 	if err != nil {